@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"repos/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		c := cache.NewLRU(2)
+
+		_ = c.Set(ctx, "a", []byte("1"), 0)
+		_ = c.Set(ctx, "b", []byte("2"), 0)
+		_, _, _ = c.Get(ctx, "a")
+		_ = c.Set(ctx, "c", []byte("3"), 0)
+
+		_, okA, _ := c.Get(ctx, "a")
+		_, okB, _ := c.Get(ctx, "b")
+		value, okC, _ := c.Get(ctx, "c")
+
+		assert.True(t, okA, "they should be equal")
+		assert.False(t, okB, "they should be equal")
+		assert.True(t, okC, "they should be equal")
+		assert.Equal(t, []byte("3"), value, "they should be equal")
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		c := cache.NewLRU(2)
+
+		_ = c.Set(ctx, "a", []byte("1"), time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		_, ok, _ := c.Get(ctx, "a")
+
+		assert.False(t, ok, "they should be equal")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		c := cache.NewLRU(2)
+
+		_ = c.Set(ctx, "a", []byte("1"), 0)
+		_ = c.Delete(ctx, "a", "unknown")
+
+		_, ok, _ := c.Get(ctx, "a")
+
+		assert.False(t, ok, "they should be equal")
+	})
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"repos/interfaces"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultInvalidationChannel = "users:invalidate"
+
+type redisConfig struct {
+	channel  string
+	local    interfaces.Cache
+	localTTL time.Duration
+}
+
+type RedisOption func(*redisConfig)
+
+func defaultRedisConfig() redisConfig {
+	return redisConfig{
+		channel:  DefaultInvalidationChannel,
+		localTTL: time.Minute,
+	}
+}
+
+// WithLocal keeps a copy of the keys read from redis in local for at most
+// ttl, or until the key expires in redis if sooner, dropping them as soon as
+// any replica writes them.
+func WithLocal(local interfaces.Cache, ttl time.Duration) RedisOption {
+	return func(c *redisConfig) {
+		c.local = local
+		c.localTTL = ttl
+	}
+}
+
+// WithInvalidationChannel sets the pub/sub channel the replicas announce their writes on.
+func WithInvalidationChannel(channel string) RedisOption {
+	return func(c *redisConfig) {
+		c.channel = channel
+	}
+}
+
+// redisCache shares the cached keys between the replicas of the service.
+// Every Set and Delete is published, prefixed with the id of the replica,
+// so the others can drop their local copy.
+type redisCache struct {
+	client redis.UniversalClient
+	config redisConfig
+	id     string
+}
+
+func NewRedis(ctx context.Context, client redis.UniversalClient, opts ...RedisOption) (interfaces.Cache, func() error, error) {
+	config := defaultRedisConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, nil, err
+	}
+
+	c := &redisCache{client: client, config: config, id: hex.EncodeToString(id)}
+	if config.local == nil {
+		return c, func() error { return nil }, nil
+	}
+
+	pubsub := client.Subscribe(ctx, config.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, err
+	}
+
+	go c.listen(pubsub.Channel())
+
+	return c, pubsub.Close, nil
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if c.config.local != nil {
+		if value, ok, err := c.config.local.Get(ctx, key); err == nil && ok {
+			return value, true, nil
+		}
+	}
+
+	if c.config.local == nil {
+		value, err := c.client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		return value, true, nil
+	}
+
+	// the local copy must not outlive the key, PTTL tells how long it has left.
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, err := get.Bytes()
+	if err != nil {
+		return nil, false, err
+	}
+
+	ttl := c.config.localTTL
+	if remaining := pttl.Val(); remaining > 0 && (ttl <= 0 || remaining < ttl) {
+		ttl = remaining
+	}
+
+	_ = c.config.local.Set(ctx, key, value, ttl)
+
+	return value, true, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return err
+	}
+
+	return c.publish(ctx, key)
+}
+
+func (c *redisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+
+	return c.publish(ctx, keys...)
+}
+
+// publish drops the keys from the local cache and announces them to the
+// other replicas.
+func (c *redisCache) publish(ctx context.Context, keys ...string) error {
+	if c.config.local == nil {
+		return nil
+	}
+
+	if err := c.config.local.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	return c.client.Publish(ctx, c.config.channel, c.id+"\n"+strings.Join(keys, "\n")).Err()
+}
+
+func (c *redisCache) listen(messages <-chan *redis.Message) {
+	for msg := range messages {
+		keys := strings.Split(msg.Payload, "\n")
+		if len(keys) < 2 || keys[0] == c.id {
+			continue
+		}
+
+		_ = c.config.local.Delete(context.Background(), keys[1:]...)
+	}
+}
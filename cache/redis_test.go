@@ -0,0 +1,197 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"repos/cache"
+	"repos/interfaces"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+type testContainerRedis struct {
+	server *miniredis.Miniredis
+}
+
+// NewTestContainerRedis runs an in process redis stand-in, so the redis
+// tests do not need docker.
+func NewTestContainerRedis(ctx context.Context) (testContainerRedis, func(ctx context.Context), error) {
+	server, err := miniredis.Run()
+
+	t := testContainerRedis{server: server}
+
+	return t, t.cleanDB, err
+}
+
+func (tcr testContainerRedis) GetConnection(ctx context.Context) string {
+	return tcr.server.Addr()
+}
+
+func (tcr testContainerRedis) cleanDB(ctx context.Context) {
+	tcr.server.Close()
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, close, err := NewTestContainerRedis(ctx)
+	if err != nil {
+		t.Fatalf("mounting redis container: %v", err)
+	}
+	defer close(ctx)
+
+	client := redis.NewClient(&redis.Options{Addr: redisContainer.GetConnection(ctx)})
+	defer client.Close()
+
+	c, closeCache, err := cache.NewRedis(ctx, client)
+	if err != nil {
+		t.Fatalf("creating cache: %v", err)
+	}
+	defer closeCache()
+
+	t.Run("set and get", func(t *testing.T) {
+		if err := c.Set(ctx, "users:1", []byte("first"), time.Minute); err != nil {
+			t.Fatalf("setting key: %v", err)
+		}
+
+		value, ok, err := c.Get(ctx, "users:1")
+
+		assert.Nil(t, err)
+		assert.True(t, ok, "they should be equal")
+		assert.Equal(t, []byte("first"), value, "they should be equal")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, ok, err := c.Get(ctx, "users:42")
+
+		assert.Nil(t, err)
+		assert.False(t, ok, "they should be equal")
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		if err := c.Set(ctx, "users:2", []byte("second"), time.Second); err != nil {
+			t.Fatalf("setting key: %v", err)
+		}
+
+		redisContainer.server.FastForward(2 * time.Second)
+
+		_, ok, _ := c.Get(ctx, "users:2")
+
+		assert.False(t, ok, "they should be equal")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_ = c.Set(ctx, "users:3", []byte("third"), 0)
+
+		if err := c.Delete(ctx, "users:3"); err != nil {
+			t.Fatalf("deleting key: %v", err)
+		}
+
+		_, ok, _ := c.Get(ctx, "users:3")
+
+		assert.False(t, ok, "they should be equal")
+	})
+}
+
+func TestRedisInvalidation(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, close, err := NewTestContainerRedis(ctx)
+	if err != nil {
+		t.Fatalf("mounting redis container: %v", err)
+	}
+	defer close(ctx)
+
+	replicas := []interfaces.Cache{}
+	newReplica := func() (*redis.Client, func() error) {
+		client := redis.NewClient(&redis.Options{Addr: redisContainer.GetConnection(ctx)})
+
+		c, closeCache, err := cache.NewRedis(ctx, client, cache.WithLocal(cache.NewLRU(10), time.Hour))
+		if err != nil {
+			t.Fatalf("creating cache: %v", err)
+		}
+
+		replicas = append(replicas, c)
+
+		return client, closeCache
+	}
+
+	clientA, closeA := newReplica()
+	defer clientA.Close()
+	defer closeA()
+
+	clientB, closeB := newReplica()
+	defer clientB.Close()
+	defer closeB()
+
+	a, b := replicas[0], replicas[1]
+
+	if err := a.Set(ctx, "users:1", []byte("first"), 0); err != nil {
+		t.Fatalf("setting key: %v", err)
+	}
+
+	value, _, _ := b.Get(ctx, "users:1")
+	assert.Equal(t, []byte("first"), value, "they should be equal")
+
+	// served from the local copy of b from now on.
+	redisContainer.server.Set("users:1", "stale")
+	value, _, _ = b.Get(ctx, "users:1")
+	assert.Equal(t, []byte("first"), value, "they should be equal")
+
+	if err := a.Set(ctx, "users:1", []byte("updated"), 0); err != nil {
+		t.Fatalf("setting key: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		value, _, _ := b.Get(ctx, "users:1")
+		return string(value) == "updated"
+	}, time.Second, 10*time.Millisecond)
+
+	if err := a.Delete(ctx, "users:1"); err != nil {
+		t.Fatalf("deleting key: %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		_, ok, _ := b.Get(ctx, "users:1")
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRedisLocalExpiry(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, close, err := NewTestContainerRedis(ctx)
+	if err != nil {
+		t.Fatalf("mounting redis container: %v", err)
+	}
+	defer close(ctx)
+
+	client := redis.NewClient(&redis.Options{Addr: redisContainer.GetConnection(ctx)})
+	defer client.Close()
+
+	c, closeCache, err := cache.NewRedis(ctx, client, cache.WithLocal(cache.NewLRU(10), time.Hour))
+	if err != nil {
+		t.Fatalf("creating cache: %v", err)
+	}
+	defer closeCache()
+
+	if err := c.Set(ctx, "users:1", []byte("first"), 20*time.Millisecond); err != nil {
+		t.Fatalf("setting key: %v", err)
+	}
+
+	value, _, _ := c.Get(ctx, "users:1")
+	assert.Equal(t, []byte("first"), value, "they should be equal")
+
+	// the local copy expires with the key, not after the hour of WithLocal.
+	time.Sleep(30 * time.Millisecond)
+	redisContainer.server.FastForward(30 * time.Millisecond)
+
+	_, ok, err := c.Get(ctx, "users:1")
+
+	assert.Nil(t, err)
+	assert.False(t, ok, "should be expired")
+}
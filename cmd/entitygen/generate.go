@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm/schema"
+)
+
+// config is where the entity is read from and how its files are named.
+type config struct {
+	// source is the file declaring the entity.
+	source string
+	// typeName is the struct of the entity.
+	typeName string
+	// plural names the repository interface, typeName with an s by default.
+	plural string
+	// entityImport is the import path of the package of the entity.
+	entityImport string
+	// interfacesImport is the import path of repos/interfaces.
+	interfacesImport string
+	// utilsImport is the import path of repos/utils.
+	utilsImport string
+	// repositoriesImport is the import path of repos/repositories.
+	repositoriesImport string
+}
+
+// field is a field of the entity annotated with the repo tag.
+type field struct {
+	Name   string
+	Column string
+	Type   string
+	ID     bool
+	Sort   bool
+	Filter bool
+}
+
+// entity is what the templates are rendered with.
+type entity struct {
+	Name    string
+	Plural  string
+	Table   string
+	File    string
+	Package string
+	Fields  []field
+	ID      field
+	// FiltersTime and SamplesTime tell whether the filters and the samples
+	// of the conformance test import time.
+	FiltersTime bool
+	SamplesTime bool
+	// Q qualifies the names of repos/interfaces from the package of the entity.
+	Q                  string
+	EntityImport       string
+	InterfacesImport   string
+	UtilsImport        string
+	RepositoriesImport string
+}
+
+// Sortable are the fields GetAll can order by, the first is the default.
+func (e entity) Sortable() []field {
+	return e.filter(func(f field) bool { return f.Sort })
+}
+
+func (e entity) Filterable() []field {
+	return e.filter(func(f field) bool { return f.Filter })
+}
+
+// Indexed are the fields other than the id that are sorted or filtered by.
+func (e entity) Indexed() []field {
+	return e.filter(func(f field) bool { return !f.ID && (f.Sort || f.Filter) })
+}
+
+func (e entity) filter(keep func(field) bool) []field {
+	fields := []field{}
+	for _, f := range e.Fields {
+		if keep(f) {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+var naming = schema.NamingStrategy{}
+
+// parseEntity reads the struct typeName of the source. Its fields are stored
+// with the gorm naming, the ones tagged repo:"id", repo:"sort" or
+// repo:"filter" (comma separated) are the id, sortable and filterable.
+func parseEntity(cfg config, src []byte) (entity, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), cfg.source, src, 0)
+	if err != nil {
+		return entity{}, err
+	}
+
+	var st *ast.StructType
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok && spec.Name.Name == cfg.typeName {
+			st, _ = spec.Type.(*ast.StructType)
+		}
+
+		return st == nil
+	})
+
+	if st == nil {
+		return entity{}, fmt.Errorf("struct %s not found in %s", cfg.typeName, cfg.source)
+	}
+
+	plural := cfg.plural
+	if plural == "" {
+		plural = cfg.typeName + "s"
+	}
+
+	e := entity{
+		Name:               cfg.typeName,
+		Plural:             plural,
+		Table:              naming.TableName(cfg.typeName),
+		File:               naming.ColumnName("", cfg.typeName),
+		Package:            file.Name.Name,
+		EntityImport:       cfg.entityImport,
+		InterfacesImport:   cfg.interfacesImport,
+		UtilsImport:        cfg.utilsImport,
+		RepositoriesImport: cfg.repositoriesImport,
+	}
+
+	if cfg.entityImport != cfg.interfacesImport {
+		e.Q = "interfaces."
+	}
+
+	var ids int
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			unquoted, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return entity{}, err
+			}
+			tag = reflect.StructTag(unquoted)
+		}
+
+		typ := typeString(f.Type)
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+
+			fd := field{Name: name.Name, Column: naming.ColumnName("", name.Name), Type: typ}
+			for _, option := range strings.Split(tag.Get("repo"), ",") {
+				switch option {
+				case "id":
+					fd.ID = true
+				case "sort":
+					fd.Sort = true
+				case "filter":
+					fd.Filter = true
+				case "":
+				default:
+					return entity{}, fmt.Errorf("field %s: unknown repo option %q", name.Name, option)
+				}
+			}
+
+			if fd.ID {
+				e.ID = fd
+				ids++
+			}
+
+			if typ == "time.Time" {
+				e.SamplesTime = true
+				e.FiltersTime = e.FiltersTime || fd.Filter
+			}
+
+			e.Fields = append(e.Fields, fd)
+		}
+	}
+
+	if ids != 1 {
+		return entity{}, fmt.Errorf("struct %s must have exactly one field tagged repo:\"id\", found %d", cfg.typeName, ids)
+	}
+
+	if !idTypes[e.ID.Type] {
+		return entity{}, fmt.Errorf("unsupported id type %s", e.ID.Type)
+	}
+
+	if len(e.Sortable()) == 0 {
+		return entity{}, errors.New("at least one field must be tagged repo:\"sort\"")
+	}
+
+	for _, f := range e.Fields {
+		if _, ok := sqlTypes[f.Type]; !ok {
+			return entity{}, fmt.Errorf("field %s: unsupported type %s", f.Name, f.Type)
+		}
+	}
+
+	return e, nil
+}
+
+// typeString prints the type of a field as written in the source.
+func typeString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return typeString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + typeString(t.X)
+	case *ast.ArrayType:
+		return "[]" + typeString(t.Elt)
+	}
+
+	return fmt.Sprintf("%T", expr)
+}
+
+// sqlTypes are the MySQL columns of the supported field types.
+var sqlTypes = map[string]string{
+	"string":    "varchar(255) NOT NULL",
+	"bool":      "tinyint(1) NOT NULL",
+	"int":       "bigint(20) NOT NULL",
+	"int8":      "tinyint NOT NULL",
+	"int16":     "smallint NOT NULL",
+	"int32":     "int NOT NULL",
+	"int64":     "bigint(20) NOT NULL",
+	"uint":      "bigint(20) UNSIGNED NOT NULL",
+	"uint8":     "TINYINT UNSIGNED NOT NULL",
+	"uint16":    "smallint UNSIGNED NOT NULL",
+	"uint32":    "int UNSIGNED NOT NULL",
+	"uint64":    "bigint(20) UNSIGNED NOT NULL",
+	"float32":   "float NOT NULL",
+	"float64":   "double NOT NULL",
+	"time.Time": "timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP",
+}
+
+// idTypes are the types an id can have.
+var idTypes = map[string]bool{
+	"string": true,
+	"int":    true,
+	"int32":  true,
+	"int64":  true,
+	"uint":   true,
+	"uint32": true,
+	"uint64": true,
+}
+
+// sample is the value of the field of the i-th entity of the conformance test.
+func sample(f field, i int) string {
+	switch f.Type {
+	case "string":
+		return strconv.Quote(fmt.Sprintf("%s-%d", f.Column, i))
+	case "bool":
+		return strconv.FormatBool(i%2 == 1)
+	case "float32", "float64":
+		return fmt.Sprintf("%d.5", i)
+	case "time.Time":
+		return fmt.Sprintf("time.Date(2024, 4, %d, 0, 0, 0, 0, time.UTC)", 10+i)
+	}
+
+	return strconv.Itoa(i)
+}
+
+var funcs = template.FuncMap{
+	"sample": sample,
+	"sqlType": func(f field) string {
+		if f.ID && f.Type != "string" {
+			return sqlTypes[f.Type] + " AUTO_INCREMENT"
+		}
+
+		return sqlTypes[f.Type]
+	},
+	"samples":  func() []int { return []int{1, 2, 3} },
+	"unexport": unexport,
+	"join":     join,
+}
+
+func unexport(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// join lists the names of the fields with the prefix.
+func join(fields []field, prefix string) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = prefix + f.Name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// generate renders the files of the entity by kind: the filters and the
+// repository interface next to the entity, the repositories, the schema and
+// the conformance test in repos/repositories. fileNames names them.
+func generate(cfg config, src []byte) (map[string][]byte, error) {
+	e, err := parseEntity(cfg, src)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	for name, tmpl := range map[string]*template.Template{
+		"entity":     entityTemplate,
+		"repository": repositoryTemplate,
+		"schema":     schemaTemplate,
+		"test":       testTemplate,
+	} {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, e); err != nil {
+			return nil, fmt.Errorf("rendering %s: %w", name, err)
+		}
+
+		out := buf.Bytes()
+		if name != "schema" {
+			if out, err = format.Source(out); err != nil {
+				return nil, fmt.Errorf("formatting %s: %w", name, err)
+			}
+		}
+
+		files[name] = out
+	}
+
+	return files, nil
+}
+
+// fileNames are the suffixes of the generated files after the snake case
+// name of the entity.
+var fileNames = map[string]string{
+	"entity":     "_gen.go",
+	"repository": "_repo_gen.go",
+	"schema":     ".sql",
+	"test":       "_repo_gen_test.go",
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+var testConfig = config{
+	source:             "testdata/team.go",
+	typeName:           "Team",
+	entityImport:       "repos/interfaces",
+	interfacesImport:   "repos/interfaces",
+	utilsImport:        "repos/utils",
+	repositoriesImport: "repos/repositories",
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := os.ReadFile(testConfig.source)
+	if err != nil {
+		t.Fatalf("reading entity: %v", err)
+	}
+
+	files, err := generate(testConfig, src)
+	if err != nil {
+		t.Fatalf("generating: %v", err)
+	}
+
+	for kind, content := range files {
+		golden := filepath.Join("testdata", "team"+fileNames[kind]+".golden")
+		if *update {
+			if err := os.WriteFile(golden, content, 0o644); err != nil {
+				t.Fatalf("writing %s: %v", golden, err)
+			}
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("reading %s: %v", golden, err)
+		}
+
+		assert.Equal(t, string(want), string(content), "they should be equal")
+	}
+}
+
+func TestGenerateQualifiesInterfaces(t *testing.T) {
+	cfg := testConfig
+	cfg.entityImport = "repos/teams"
+
+	files, err := generate(cfg, []byte("package teams\n\ntype Team struct {\n\tID int64 `repo:\"id,sort\"`\n}\n"))
+	if err != nil {
+		t.Fatalf("generating: %v", err)
+	}
+
+	assert.Contains(t, string(files["entity"]), `"repos/interfaces"`)
+	assert.Contains(t, string(files["entity"]), "interfaces.Repo[Team, int64, TeamFilters]")
+	assert.Contains(t, string(files["schema"]), "`id` bigint(20) NOT NULL AUTO_INCREMENT")
+}
+
+func TestGenerateInvalidEntity(t *testing.T) {
+	for name, src := range map[string]string{
+		"missing struct":   "package interfaces\n",
+		"without id":       "package interfaces\n\ntype Team struct {\n\tName string `repo:\"sort\"`\n}\n",
+		"without sort":     "package interfaces\n\ntype Team struct {\n\tCode string `repo:\"id\"`\n}\n",
+		"unknown option":   "package interfaces\n\ntype Team struct {\n\tCode string `repo:\"id,sort,unique\"`\n}\n",
+		"unsupported type": "package interfaces\n\ntype Team struct {\n\tCode string `repo:\"id,sort\"`\n\tTags []string\n}\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := generate(testConfig, []byte(src))
+
+			assert.Error(t, err)
+		})
+	}
+}
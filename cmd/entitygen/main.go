@@ -0,0 +1,120 @@
+// Command entitygen generates the repositories of an entity from its struct,
+// the fields are annotated with the repo tag:
+//
+//	//go:generate go run repos/cmd/entitygen -type Team
+//	type Team struct {
+//		Code    string `repo:"id"`
+//		Name    string `repo:"sort"`
+//		Members int    `repo:"sort,filter"`
+//	}
+//
+// Next to the entity it writes team_gen.go with TeamFilters, the TeamOrderBy
+// constants, TeamEntity and the TeamsRepo interface. In repos/repositories it
+// writes the MySQL and Mongo repositories, built on the generic ones, the
+// MySQL schema team.sql and a conformance test of both backends.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		typeName     = flag.String("type", "", "struct of the entity")
+		plural       = flag.String("plural", "", "plural of the entity naming its repository interface, the type with an s by default")
+		source       = flag.String("source", os.Getenv("GOFILE"), "file declaring the entity")
+		repositories = flag.String("repositories", "", "directory of repos/repositories, found from go.mod by default")
+	)
+	flag.Parse()
+
+	if *typeName == "" || *source == "" {
+		log.Fatalf("-type and -source are required")
+	}
+
+	src, err := os.ReadFile(*source)
+	if err != nil {
+		log.Fatalf("reading entity: %s", err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(*source))
+	if err != nil {
+		log.Fatalf("resolving entity directory: %s", err)
+	}
+
+	root, module, err := findModule(dir)
+	if err != nil {
+		log.Fatalf("finding module: %s", err)
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		log.Fatalf("resolving entity package: %s", err)
+	}
+
+	if *repositories == "" {
+		*repositories = filepath.Join(root, "repositories")
+	}
+
+	cfg := config{
+		source:             *source,
+		typeName:           *typeName,
+		plural:             *plural,
+		entityImport:       path.Join(module, filepath.ToSlash(rel)),
+		interfacesImport:   path.Join(module, "interfaces"),
+		utilsImport:        path.Join(module, "utils"),
+		repositoriesImport: path.Join(module, "repositories"),
+	}
+
+	files, err := generate(cfg, src)
+	if err != nil {
+		log.Fatalf("generating %s: %s", *typeName, err)
+	}
+
+	prefix := naming.ColumnName("", *typeName)
+	for kind, content := range files {
+		out := filepath.Join(*repositories, prefix+fileNames[kind])
+		if kind == "entity" {
+			out = filepath.Join(dir, prefix+fileNames[kind])
+		}
+
+		if err := os.WriteFile(out, content, 0o644); err != nil {
+			log.Fatalf("writing %s: %s", out, err)
+		}
+
+		fmt.Println(out)
+	}
+}
+
+// findModule walks up from dir to the go.mod, returning its directory and
+// the module path.
+func findModule(dir string) (string, string, error) {
+	for {
+		f, err := os.Open(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			defer f.Close()
+
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+					return dir, strings.Trim(strings.TrimSpace(module), `"`), nil
+				}
+			}
+
+			return "", "", fmt.Errorf("no module directive in %s", f.Name())
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
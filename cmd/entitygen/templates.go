@@ -0,0 +1,198 @@
+package main
+
+import "text/template"
+
+var entityTemplate = template.Must(template.New("entity").Funcs(funcs).Parse(`// Code generated by entitygen. DO NOT EDIT.
+
+package {{.Package}}
+{{if or .Q .FiltersTime}}
+import (
+{{- if .FiltersTime}}
+	"time"
+{{end}}
+{{- if .Q}}
+	"{{.InterfacesImport}}"
+{{- end}}
+)
+{{end}}
+const (
+{{- range .Fields}}
+	{{$.Name}}Field{{.Name}} = "{{.Column}}"
+{{- end}}
+)
+
+// {{.Name}}Fields are the columns of {{.Name}} that can be selected with utils.WithFields.
+var {{.Name}}Fields = []string{ {{- join .Fields (print .Name "Field") -}} }
+
+type {{.Name}}OrderBy string
+
+const (
+{{- range .Sortable}}
+	{{$.Name}}OrderBy{{.Name}} {{$.Name}}OrderBy = "{{.Column}}"
+{{- end}}
+)
+
+type {{.Name}}Filters struct {
+	Offset  int
+	Limit   int
+	OrderBy {{.Name}}OrderBy
+{{- range .Filterable}}
+	{{.Name}}Gte {{.Type}}
+	{{.Name}}Lte {{.Type}}
+{{- end}}
+	IDs []{{.ID.Type}}
+}
+
+// {{.Name}}Entity is the metadata the generic repositories store the {{.Table}} with.
+var {{.Name}}Entity = {{.Q}}Entity[{{.Name}}, {{.ID.Type}}]{
+	Table:      "{{.Table}}",
+	IDField:    {{.Name}}Field{{.ID.Name}},
+	ID:         func(e *{{.Name}}) {{.ID.Type}} { return e.{{.ID.Name}} },
+	Fields:     {{.Name}}Fields,
+	Sortable:   []string{ {{- join .Sortable (print .Name "Field") -}} },
+	Filterable: []string{ {{- join .Filterable (print .Name "Field") -}} },
+}
+
+// Query translates the filters to the generic query of {{.Name}}Entity.
+func (f {{.Name}}Filters) Query() ({{.Q}}Query[{{.ID.Type}}], error) {
+	query := {{.Q}}Query[{{.ID.Type}}]{
+		Offset:  f.Offset,
+		Limit:   f.Limit,
+		OrderBy: string(f.OrderBy),
+		IDs:     f.IDs,
+	}
+{{range .Filterable}}
+	if r := ({{$.Q}}Range{Field: {{$.Name}}Field{{.Name}}, Gte: {{$.Q}}Bound(f.{{.Name}}Gte), Lte: {{$.Q}}Bound(f.{{.Name}}Lte)}); r.Gte != nil || r.Lte != nil {
+		query.Ranges = append(query.Ranges, r)
+	}
+{{end}}
+	return {{.Name}}Entity.Normalize(query)
+}
+
+// {{.Plural}}Repo is the repository of the {{.Table}}.
+type {{.Plural}}Repo interface {
+	{{.Q}}Repo[{{.Name}}, {{.ID.Type}}, {{.Name}}Filters]
+}
+`))
+
+var repositoryTemplate = template.Must(template.New("repository").Funcs(funcs).Parse(`// Code generated by entitygen. DO NOT EDIT.
+
+package repositories
+
+import (
+	"context"
+
+	"{{.EntityImport}}"
+	"{{.UtilsImport}}"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+{{- $type := print .Package "." .Name}}
+{{- $repo := unexport .Name}}
+
+// {{$repo}}RepoMysql is the generic gorm repository instantiated for the {{.Table}}.
+type {{$repo}}RepoMysql struct {
+	gormRepo[{{$type}}, {{.ID.Type}}]
+}
+
+func New{{.Name}}RepoMysql(db *gorm.DB) {{.Package}}.{{.Plural}}Repo {
+	return &{{$repo}}RepoMysql{gormRepo[{{$type}}, {{.ID.Type}}]{db: db, dialect: mysqlDialect, entity: {{.Package}}.{{.Name}}Entity}}
+}
+
+func (r {{$repo}}RepoMysql) GetAll(ctx context.Context, filters {{$type}}Filters, opts ...utils.Options) ([]*{{$type}}, int64, error) {
+	query, err := filters.Query()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.gormRepo.GetAll(ctx, query, opts...)
+}
+
+// {{$repo}}RepoMongo is the generic mongo repository instantiated for the {{.Table}}.
+type {{$repo}}RepoMongo struct {
+	mongoRepo[{{$type}}, {{.ID.Type}}]
+}
+
+func New{{.Name}}RepoMongo(db *mongo.Database) {{.Package}}.{{.Plural}}Repo {
+	return &{{$repo}}RepoMongo{mongoRepo[{{$type}}, {{.ID.Type}}]{
+		collection: db.Collection({{.Package}}.{{.Name}}Entity.Table),
+		entity:     {{.Package}}.{{.Name}}Entity,
+	}}
+}
+
+func (r {{$repo}}RepoMongo) GetAll(ctx context.Context, filters {{$type}}Filters, opts ...utils.Options) ([]*{{$type}}, int64, error) {
+	query, err := filters.Query()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.mongoRepo.GetAll(ctx, query, opts...)
+}
+`))
+
+var schemaTemplate = template.Must(template.New("schema").Funcs(funcs).Parse(`-- Code generated by entitygen. DO NOT EDIT.
+
+CREATE TABLE ` + "`{{.Table}}`" + ` (
+{{- range .Fields}}
+  ` + "`{{.Column}}`" + ` {{sqlType .}},
+{{- end}}
+  PRIMARY KEY (` + "`{{.ID.Column}}`" + `)
+{{- range .Indexed}},
+  KEY ` + "`idx_{{$.Table}}_{{.Column}}` (`{{.Column}}`)" + `
+{{- end}}
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`))
+
+var testTemplate = template.Must(template.New("test").Funcs(funcs).Parse(`// Code generated by entitygen. DO NOT EDIT.
+
+package repositories_test
+
+import (
+	"os"
+	"testing"
+{{- if .SamplesTime}}
+	"time"
+{{- end}}
+
+	"{{.EntityImport}}"
+	"{{.RepositoriesImport}}"
+
+	"gorm.io/gorm"
+)
+
+{{- $type := print .Package "." .Name}}
+{{- $samples := print (unexport .Name) "Samples"}}
+
+func {{$samples}}() []*{{$type}} {
+	return []*{{$type}}{
+{{- range $i := samples}}
+		{ {{- range $j, $f := $.Fields}}{{if $j}}, {{end}}{{$f.Name}}: {{sample $f $i}}{{end -}} },
+{{- end}}
+	}
+}
+
+func Test{{.Name}}RepoMysqlConformance(t *testing.T) {
+	t.Parallel()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	schema, err := os.ReadFile("{{.File}}.sql")
+	if err != nil {
+		t.Fatalf("reading schema: %v", err)
+	}
+
+	if err := db.Exec(string(schema)).Error; err != nil {
+		t.Fatalf("creating {{.Table}} table: %v", err)
+	}
+
+	testRepoConformance[{{$type}}, {{.ID.Type}}, {{$type}}Filters](t, repositories.New{{.Name}}RepoMysql(db), {{.Package}}.{{.Name}}Entity, {{$samples}}(), {{sample .ID 0}}, {{$type}}Filters{})
+}
+
+func Test{{.Name}}RepoMongoConformance(t *testing.T) {
+	t.Parallel()
+
+	testRepoConformance[{{$type}}, {{.ID.Type}}, {{$type}}Filters](t, repositories.New{{.Name}}RepoMongo(newTestMongo(t)), {{.Package}}.{{.Name}}Entity, {{$samples}}(), {{sample .ID 0}}, {{$type}}Filters{})
+}
+`))
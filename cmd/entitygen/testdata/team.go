@@ -0,0 +1,11 @@
+package interfaces
+
+import "time"
+
+type Team struct {
+	Code      string `repo:"id"`
+	Name      string `repo:"sort"`
+	Members   int    `repo:"sort,filter"`
+	Active    bool
+	CreatedAt time.Time `repo:"filter"`
+}
@@ -0,0 +1,127 @@
+// Command usersync copies the users from one backend to another, or verifies
+// that both hold the same users.
+//
+//	usersync -from mysql -to mongo -mysql-dsn 'user:pass@tcp(localhost:3306)/db?parseTime=True' -mongo-uri mongodb://localhost:27017
+//
+// The copy is walked by ranges of ids and saved to the checkpoint after each
+// one, running it again resumes from there. Remove the checkpoint to start
+// over. With -verify it prints the ranges whose count or checksum differ and
+// exits with status 1 when there is any.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"repos/interfaces"
+	"repos/repositories"
+
+	"github.com/glebarez/sqlite"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	var (
+		from       = flag.String("from", "mysql", "backend to read the users from: mysql, mongo or sqlite")
+		to         = flag.String("to", "mongo", "backend to write the users to: mysql, mongo or sqlite")
+		mysqlDSN   = flag.String("mysql-dsn", os.Getenv("MYSQL_DSN"), "MySQL data source name")
+		mongoURI   = flag.String("mongo-uri", os.Getenv("MONGO_URI"), "MongoDB connection string")
+		mongoDB    = flag.String("mongo-database", "test", "MongoDB database holding the users collection")
+		sqlitePath = flag.String("sqlite-path", "users.db", "SQLite database file, created when missing")
+		verify     = flag.Bool("verify", false, "compare both backends instead of copying")
+		cfg        config
+	)
+	flag.IntVar(&cfg.batch, "batch", 500, "number of ids read per range")
+	flag.Float64Var(&cfg.rate, "rate", 0, "maximum users written per second, 0 for no limit")
+	flag.StringVar(&cfg.checkpoint, "checkpoint", "usersync.checkpoint", "file saving the progress of the copy")
+	flag.Int64Var(&cfg.maxID, "max-id", 0, "highest id to copy, by default the highest of both backends")
+	flag.Parse()
+
+	if cfg.batch <= 0 {
+		log.Fatalf("batch must be positive")
+	}
+
+	if *from == *to {
+		log.Fatalf("from and to must be different backends")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	backends := map[string]func() (interfaces.UsersRepo, error){
+		"mysql": func() (interfaces.UsersRepo, error) {
+			db, err := gorm.Open(mysql.Open(*mysqlDSN), &gorm.Config{Logger: logger.Discard})
+			if err != nil {
+				return nil, err
+			}
+
+			return repositories.NewUserRepoMysql(db), nil
+		},
+		"mongo": func() (interfaces.UsersRepo, error) {
+			client, err := mongo.Connect(ctx, options.Client().ApplyURI(*mongoURI))
+			if err != nil {
+				return nil, err
+			}
+
+			return repositories.NewUserRepoMongo(client.Database(*mongoDB)), nil
+		},
+		"sqlite": func() (interfaces.UsersRepo, error) {
+			db, err := gorm.Open(sqlite.Open(*sqlitePath), &gorm.Config{Logger: logger.Discard})
+			if err != nil {
+				return nil, err
+			}
+
+			if err := repositories.MigrateSQLite(ctx, db); err != nil {
+				return nil, err
+			}
+
+			return repositories.NewUserRepoSQLite(db), nil
+		},
+	}
+
+	open := func(name string) interfaces.UsersRepo {
+		connect, ok := backends[name]
+		if !ok {
+			log.Fatalf("unknown backend %q", name)
+		}
+
+		repo, err := connect()
+		if err != nil {
+			log.Fatalf("connecting to %s: %s", name, err)
+		}
+
+		return repo
+	}
+
+	source, target := open(*from), open(*to)
+
+	if !*verify {
+		if err := syncUsers(ctx, source, target, cfg); err != nil {
+			log.Fatalf("sync failed: %s", err)
+		}
+
+		return
+	}
+
+	mismatches, err := verifyUsers(ctx, source, target, cfg)
+	if err != nil {
+		log.Fatalf("verify failed: %s", err)
+	}
+
+	for _, m := range mismatches {
+		fmt.Printf("ids %d-%d: %s has %d users (%s), %s has %d users (%s)\n",
+			m.From, m.To, *from, m.Source.Count, m.Source.Sum, *to, m.Target.Count, m.Target.Sum)
+	}
+
+	if len(mismatches) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"repos/interfaces"
+)
+
+type config struct {
+	batch      int
+	rate       float64
+	checkpoint string
+	maxID      int64
+}
+
+// checkpoint is the first id of the range left to copy.
+type checkpoint struct {
+	Next int64 `json:"next"`
+}
+
+func readCheckpoint(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 1, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	var c checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return 0, fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+
+	return c.Next, nil
+}
+
+// writeCheckpoint replaces the checkpoint by renaming, so a crash never
+// leaves it half written.
+func writeCheckpoint(path string, next int64) error {
+	data, err := json.Marshal(checkpoint{Next: next})
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// maxID is the configured bound, or the highest id of any of the backends.
+func maxID(ctx context.Context, cfg config, repos ...interfaces.UsersRepo) (int64, error) {
+	if cfg.maxID > 0 {
+		return cfg.maxID, nil
+	}
+
+	var max int64
+	for _, repo := range repos {
+		r, ok := repo.(interfaces.MaxIDer)
+		if !ok {
+			return 0, errors.New("the backend does not report its highest id, set -max-id")
+		}
+
+		id, err := r.MaxID(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		if id > max {
+			max = id
+		}
+	}
+
+	return max, nil
+}
+
+// idRange lists the ids from start to end, both included.
+func idRange(start, end int64) []int64 {
+	ids := make([]int64, 0, end-start+1)
+	for id := start; id <= end; id++ {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// userLine renders the fields copied between the backends. The timestamps
+// keep the precision of the MySQL columns.
+func userLine(u *interfaces.User) string {
+	return fmt.Sprintf("%d|%s|%d|%d|%d", u.ID, u.Name, u.Age, u.CreatedAt.Unix(), u.UpdatedAt.Unix())
+}
+
+// syncUsers makes the target hold the same users as the source, walking them
+// by ranges of ids from the checkpoint. Each range is compared before being
+// written, so running it again over a range already copied is a no-op.
+func syncUsers(ctx context.Context, from, to interfaces.UsersRepo, cfg config) error {
+	next, err := readCheckpoint(cfg.checkpoint)
+	if err != nil {
+		return err
+	}
+
+	max, err := maxID(ctx, cfg, from, to)
+	if err != nil {
+		return err
+	}
+
+	var (
+		start  = time.Now()
+		copied int
+	)
+	for ; next <= max; next += int64(cfg.batch) {
+		end := min(next+int64(cfg.batch)-1, max)
+
+		n, err := syncRange(ctx, from, to, idRange(next, end))
+		if err != nil {
+			return fmt.Errorf("syncing ids %d-%d: %w", next, end, err)
+		}
+
+		if err := writeCheckpoint(cfg.checkpoint, end+1); err != nil {
+			return err
+		}
+
+		copied += n
+		slog.InfoContext(ctx, "synced range", slog.Int64("from", next), slog.Int64("to", end), slog.Int("written", n))
+
+		if err := throttle(ctx, cfg.rate, start, copied); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncRange updates in place the users of the target different in the
+// source, creates those it is missing and deletes those the source no longer
+// holds, so a failure halfway never loses a user of the target. It returns
+// the number of users written.
+func syncRange(ctx context.Context, from, to interfaces.UsersRepo, ids []int64) (int, error) {
+	source, _, err := from.GetByIDs(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	target, _, err := to.GetByIDs(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	existing := make(map[uint]string, len(target))
+	for _, u := range target {
+		existing[u.ID] = userLine(u)
+	}
+
+	written := 0
+	for _, u := range source {
+		line, ok := existing[u.ID]
+		delete(existing, u.ID)
+
+		switch {
+		case ok && line == userLine(u):
+			continue
+		case ok:
+			err = to.Update(ctx, u, map[string]interface{}{
+				interfaces.FieldName:      u.Name,
+				interfaces.FieldAge:       u.Age,
+				interfaces.FieldCreatedAt: u.CreatedAt,
+				interfaces.FieldUpdatedAt: u.UpdatedAt,
+			})
+		default:
+			err = to.Create(ctx, u)
+		}
+		if err != nil {
+			return written, err
+		}
+
+		written++
+	}
+
+	removed := make([]int64, 0, len(existing))
+	for id := range existing {
+		removed = append(removed, int64(id))
+	}
+
+	if len(removed) > 0 {
+		if err := to.Delete(ctx, removed); err != nil {
+			return written, err
+		}
+	}
+
+	return written + len(removed), nil
+}
+
+// throttle waits until copying n users since start respects rate users per
+// second, zero disables it.
+func throttle(ctx context.Context, rate float64, start time.Time, n int) error {
+	if rate <= 0 {
+		return nil
+	}
+
+	wait := time.Duration(float64(n)/rate*float64(time.Second)) - time.Since(start)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rangeSum is the number of users of a range of ids and the checksum of their fields.
+type rangeSum struct {
+	Count int
+	Sum   string
+}
+
+func sumRange(ctx context.Context, repo interfaces.UsersRepo, ids []int64) (rangeSum, error) {
+	users, _, err := repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return rangeSum{}, err
+	}
+
+	lines := make([]string, len(users))
+	for i, u := range users {
+		lines[i] = userLine(u)
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line + "\n"))
+	}
+
+	return rangeSum{Count: len(users), Sum: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// mismatch is a range of ids whose users differ between the backends.
+type mismatch struct {
+	From, To       int64
+	Source, Target rangeSum
+}
+
+// verifyUsers compares the count and the checksum of every range of ids in
+// both backends, returning the ranges that differ.
+func verifyUsers(ctx context.Context, from, to interfaces.UsersRepo, cfg config) ([]mismatch, error) {
+	max, err := maxID(ctx, cfg, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := []mismatch{}
+	for next := int64(1); next <= max; next += int64(cfg.batch) {
+		end := min(next+int64(cfg.batch)-1, max)
+		ids := idRange(next, end)
+
+		source, err := sumRange(ctx, from, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := sumRange(ctx, to, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		if source != target {
+			mismatches = append(mismatches, mismatch{From: next, To: end, Source: source, Target: target})
+		}
+	}
+
+	return mismatches, nil
+}
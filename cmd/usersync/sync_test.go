@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryUsersRepo holds the users in memory, it only implements the methods
+// used by the sync.
+type memoryUsersRepo struct {
+	interfaces.UsersRepo
+	users     map[int64]interfaces.User
+	writes    int
+	createErr error
+}
+
+func newMemoryUsersRepo(users ...interfaces.User) *memoryUsersRepo {
+	m := &memoryUsersRepo{users: map[int64]interfaces.User{}}
+	for _, u := range users {
+		m.users[int64(u.ID)] = u
+	}
+
+	return m
+}
+
+func (m *memoryUsersRepo) MaxID(ctx context.Context) (int64, error) {
+	var highest int64
+	for id := range m.users {
+		highest = max(highest, id)
+	}
+
+	return highest, nil
+}
+
+func (m *memoryUsersRepo) GetByIDs(ctx context.Context, ids []int64, opts ...utils.Options) ([]*interfaces.User, []int64, error) {
+	users, missing := []*interfaces.User{}, []int64{}
+	for _, id := range ids {
+		u, ok := m.users[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		users = append(users, &u)
+	}
+
+	return users, missing, nil
+}
+
+func (m *memoryUsersRepo) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+
+	m.writes++
+	m.users[int64(user.ID)] = *user
+
+	return nil
+}
+
+func (m *memoryUsersRepo) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	m.writes++
+
+	u := m.users[int64(user.ID)]
+	for k, v := range vals {
+		switch k {
+		case interfaces.FieldName:
+			u.Name = v.(string)
+		case interfaces.FieldAge:
+			u.Age = v.(uint8)
+		case interfaces.FieldCreatedAt:
+			u.CreatedAt = v.(time.Time)
+		case interfaces.FieldUpdatedAt:
+			u.UpdatedAt = v.(time.Time)
+		}
+	}
+	m.users[int64(user.ID)] = u
+
+	return nil
+}
+
+func (m *memoryUsersRepo) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	m.writes++
+	for _, id := range ids {
+		delete(m.users, id)
+	}
+
+	return nil
+}
+
+func TestSyncUsers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	source := newMemoryUsersRepo(
+		interfaces.User{ID: 1, Name: "first", Age: 20, CreatedAt: now},
+		interfaces.User{ID: 2, Name: "second", Age: 30, CreatedAt: now},
+		interfaces.User{ID: 5, Name: "five", Age: 40, CreatedAt: now},
+	)
+	target := newMemoryUsersRepo(
+		interfaces.User{ID: 2, Name: "outdated", Age: 30, CreatedAt: now},
+		interfaces.User{ID: 3, Name: "deleted", Age: 30, CreatedAt: now},
+	)
+	cfg := config{batch: 2, checkpoint: filepath.Join(t.TempDir(), "checkpoint")}
+
+	mismatches, err := verifyUsers(ctx, source, target, cfg)
+	assert.Nil(t, err, "should be nil")
+	assert.Equal(t, 3, len(mismatches), "they should be equal")
+	assert.Equal(t, int64(1), mismatches[0].From, "they should be equal")
+	assert.Equal(t, 2, mismatches[0].Source.Count, "they should be equal")
+	assert.Equal(t, 1, mismatches[0].Target.Count, "they should be equal")
+
+	if err := syncUsers(ctx, source, target, cfg); err != nil {
+		t.Fatalf("sync users: %v", err)
+	}
+
+	assert.Equal(t, source.users, target.users, "they should be equal")
+
+	next, err := readCheckpoint(cfg.checkpoint)
+	assert.Nil(t, err, "should be nil")
+	assert.Equal(t, int64(6), next, "they should be equal")
+
+	mismatches, err = verifyUsers(ctx, source, target, cfg)
+	assert.Nil(t, err, "should be nil")
+	assert.Equal(t, 0, len(mismatches), "they should be equal")
+
+	t.Run("resumes from the checkpoint", func(t *testing.T) {
+		source.users[6] = interfaces.User{ID: 6, Name: "six", CreatedAt: now}
+		writes := target.writes
+
+		if err := syncUsers(ctx, source, target, cfg); err != nil {
+			t.Fatalf("sync users: %v", err)
+		}
+
+		assert.Equal(t, writes+1, target.writes, "they should be equal")
+		assert.Equal(t, source.users, target.users, "they should be equal")
+	})
+
+	t.Run("ranges already copied are not written", func(t *testing.T) {
+		writes := target.writes
+		cfg.checkpoint = filepath.Join(t.TempDir(), "checkpoint")
+
+		if err := syncUsers(ctx, source, target, cfg); err != nil {
+			t.Fatalf("sync users: %v", err)
+		}
+
+		assert.Equal(t, writes, target.writes, "they should be equal")
+	})
+
+	t.Run("a failed write keeps the stale users", func(t *testing.T) {
+		source := newMemoryUsersRepo(
+			interfaces.User{ID: 1, Name: "first", Age: 20, CreatedAt: now},
+			interfaces.User{ID: 2, Name: "new", Age: 30, CreatedAt: now},
+		)
+		target := newMemoryUsersRepo(interfaces.User{ID: 1, Name: "outdated", Age: 20, CreatedAt: now})
+		target.createErr = errors.New("connection refused")
+		cfg := config{batch: 2, checkpoint: filepath.Join(t.TempDir(), "checkpoint")}
+
+		err := syncUsers(ctx, source, target, cfg)
+		assert.Error(t, err, "should fail")
+
+		assert.Equal(t, map[int64]interfaces.User{1: source.users[1]}, target.users, "they should be equal")
+	})
+}
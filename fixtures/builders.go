@@ -0,0 +1,89 @@
+package fixtures
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"repos/interfaces"
+)
+
+// firstID is the id of the first user built by NewUser, far enough from the
+// canonical users not to collide with them.
+const firstID = 1000
+
+var lastID atomic.Uint64
+
+func init() {
+	lastID.Store(firstID - 1)
+}
+
+type UserOption func(*interfaces.User)
+
+func WithID(id uint) UserOption {
+	return func(u *interfaces.User) {
+		u.ID = id
+	}
+}
+
+func WithName(name string) UserOption {
+	return func(u *interfaces.User) {
+		u.Name = name
+	}
+}
+
+func WithAge(age uint8) UserOption {
+	return func(u *interfaces.User) {
+		u.Age = age
+	}
+}
+
+// WithCreatedAt sets when the user was created and last updated.
+func WithCreatedAt(at time.Time) UserOption {
+	return func(u *interfaces.User) {
+		u.CreatedAt = at
+		u.UpdatedAt = at
+	}
+}
+
+func WithUpdatedAt(at time.Time) UserOption {
+	return func(u *interfaces.User) {
+		u.UpdatedAt = at
+	}
+}
+
+// NewUser builds a user with the next free id, a name derived from it, an
+// adult age and a creation time inside the default created_at window, all
+// random where it makes sense, then applies opts.
+func NewUser(opts ...UserOption) interfaces.User {
+	id := uint(lastID.Add(1))
+
+	window, _ := interfaces.Filters{}.Normalize()
+	span := window.CreatedAtLte.Sub(window.CreatedAtGte)
+	created := window.CreatedAtGte.Add(time.Duration(rand.Int63n(int64(span)))).Truncate(time.Second)
+
+	u := interfaces.User{
+		ID:        id,
+		Name:      fmt.Sprintf("user-%d", id),
+		Age:       uint8(18 + rand.Intn(63)),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+
+	for _, opt := range opts {
+		opt(&u)
+	}
+
+	return u
+}
+
+// NewUsers builds n users with NewUser, applying opts to each of them.
+func NewUsers(n int, opts ...UserOption) []interfaces.User {
+	users := make([]interfaces.User, n)
+	for i := range users {
+		users[i] = NewUser(opts...)
+	}
+
+	return users
+}
@@ -0,0 +1,93 @@
+// Package fixtures defines the users the repository tests run against, once
+// for every backend. Users returns the canonical users the assertions are
+// written for, NewUser builds more with random defaults and Setup loads them
+// into any UsersRepo after removing what the previous test left.
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"repos/interfaces"
+)
+
+// chunk is the number of ids Reset deletes per statement.
+const chunk = 1000
+
+// Users returns the canonical users, all inside the default created_at
+// window of the filters. Each call returns new copies.
+func Users() []interfaces.User {
+	at := func(day, sec int) time.Time {
+		return time.Date(2024, 4, day, 23, 0, sec, 0, time.Local)
+	}
+
+	return []interfaces.User{
+		{ID: 1, Name: "first", Age: 55, CreatedAt: at(10, 2), UpdatedAt: at(10, 2)},
+		{ID: 2, Name: "second", Age: 22, CreatedAt: at(11, 2), UpdatedAt: at(11, 2)},
+		{ID: 3, Name: "third", Age: 40, CreatedAt: at(12, 20), UpdatedAt: at(12, 22)},
+		{ID: 4, Name: "forth", Age: 30, CreatedAt: at(13, 20), UpdatedAt: at(13, 22)},
+		{ID: 5, Name: "five", Age: 45, CreatedAt: at(14, 20), UpdatedAt: at(14, 20)},
+		{ID: 6, Name: "six", Age: 66, CreatedAt: at(15, 20), UpdatedAt: at(15, 20)},
+	}
+}
+
+// Load creates the users in repo, in order. The users keep their ids so the
+// assertions can refer to them.
+func Load(ctx context.Context, repo interfaces.UsersRepo, users ...interfaces.User) error {
+	for _, u := range users {
+		u := u
+		if err := repo.Create(ctx, &u); err != nil {
+			return fmt.Errorf("loading user %d: %w", u.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Reset deletes every user of repo, which must implement interfaces.MaxIDer.
+// The ids generated by the database are not reset, the tests creating users
+// without an id should read it back from the user.
+func Reset(ctx context.Context, repo interfaces.UsersRepo) error {
+	maxIDer, ok := repo.(interfaces.MaxIDer)
+	if !ok {
+		return errors.New("resetting users: repository does not implement MaxIDer")
+	}
+
+	max, err := maxIDer.MaxID(ctx)
+	if err != nil {
+		return fmt.Errorf("resetting users: %w", err)
+	}
+
+	for from := int64(1); from <= max; from += chunk {
+		ids := make([]int64, 0, chunk)
+		for id := from; id <= max && id < from+chunk; id++ {
+			ids = append(ids, id)
+		}
+
+		if err := repo.Delete(ctx, ids); err != nil {
+			return fmt.Errorf("resetting users: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Setup resets repo and loads the users, failing the test on error. It is
+// meant to be called at the start of every test or subtest sharing a
+// database.
+func Setup(t testing.TB, repo interfaces.UsersRepo, users ...interfaces.User) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if err := Reset(ctx, repo); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := Load(ctx, repo, users...); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package fixtures_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/repositories"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newSQLiteRepo(t *testing.T) interfaces.UsersRepo {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "users.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+
+	if err := repositories.MigrateSQLite(context.Background(), db); err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	return repositories.NewUserRepoSQLite(db)
+}
+
+func TestUsers(t *testing.T) {
+	window, err := interfaces.Filters{}.Normalize()
+	if err != nil {
+		t.Fatalf("normalizing filters: %v", err)
+	}
+
+	for _, u := range fixtures.Users() {
+		assert.True(t, !u.CreatedAt.Before(window.CreatedAtGte) && !u.CreatedAt.After(window.CreatedAtLte), "should be in the default window")
+		assert.False(t, u.UpdatedAt.Before(u.CreatedAt), "should not be updated before being created")
+	}
+}
+
+func TestNewUser(t *testing.T) {
+	window, err := interfaces.Filters{}.Normalize()
+	if err != nil {
+		t.Fatalf("normalizing filters: %v", err)
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		users := fixtures.NewUsers(20)
+
+		seen := map[uint]bool{}
+		for _, u := range users {
+			assert.False(t, seen[u.ID], "ids should be unique")
+			seen[u.ID] = true
+
+			assert.GreaterOrEqual(t, u.ID, uint(1000), "should not collide with the canonical users")
+			assert.NotEmpty(t, u.Name, "should not be empty")
+			assert.GreaterOrEqual(t, u.Age, uint8(18), "should be an adult")
+			assert.True(t, !u.CreatedAt.Before(window.CreatedAtGte) && !u.CreatedAt.After(window.CreatedAtLte), "should be in the default window")
+			assert.Equal(t, u.CreatedAt, u.UpdatedAt, "they should be equal")
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		at := time.Date(2024, 4, 16, 10, 0, 0, 0, time.Local)
+
+		u := fixtures.NewUser(fixtures.WithID(42), fixtures.WithName("john"), fixtures.WithAge(7), fixtures.WithCreatedAt(at))
+
+		assert.Equal(t, interfaces.User{ID: 42, Name: "john", Age: 7, CreatedAt: at, UpdatedAt: at}, u, "they should be equal")
+	})
+}
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+	r := newSQLiteRepo(t)
+
+	t.Run("load", func(t *testing.T) {
+		fixtures.Setup(t, r, fixtures.Users()...)
+
+		users, total, err := r.GetAll(ctx, interfaces.Filters{})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Equal(t, "six", users[0].Name, "they should be equal")
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		extra := fixtures.NewUser(fixtures.WithName("extra"))
+		fixtures.Setup(t, r, extra)
+
+		users, total, err := r.GetAll(ctx, interfaces.Filters{})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, int64(1), total, "they should be equal")
+		assert.Equal(t, extra.ID, users[0].ID, "they should be equal")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		fixtures.Setup(t, r)
+
+		_, total, err := r.GetAll(ctx, interfaces.Filters{})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, int64(0), total, "they should be equal")
+	})
+}
+
+func TestReset(t *testing.T) {
+	t.Run("without MaxIDer", func(t *testing.T) {
+		err := fixtures.Reset(context.Background(), mocks.NewRecordingUsersRepo())
+
+		assert.Error(t, err, "should fail")
+	})
+}
@@ -0,0 +1,103 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+type UserEventType string
+
+const (
+	UserCreated UserEventType = "UserCreated"
+	UserUpdated UserEventType = "UserUpdated"
+	UserDeleted UserEventType = "UserDeleted"
+)
+
+// UserEvent is a change made to a user, as told to the other services. The
+// payload holds the columns of the created user, the changed columns of an
+// updated one and the id of a deleted one.
+type UserEvent struct {
+	Type       UserEventType
+	UserID     int64
+	Payload    map[string]interface{}
+	OccurredAt time.Time
+}
+
+// UserChange is an event delivered by Watch. Token is the position of the
+// event in the stream of changes, watching again from it delivers the
+// events following this one. The last change of a stream ending on an
+// error only holds the error.
+type UserChange struct {
+	UserEvent
+	Token string
+	Err   error
+}
+
+// Watcher is implemented by the repositories able to stream the changes of
+// the users.
+type Watcher interface {
+	// Watch delivers the changes of the users matching the filters, see
+	// Filters.Matches, until ctx is done and the channel closed.
+	Watch(ctx context.Context, filters Filters) (<-chan UserChange, error)
+}
+
+// ValidateWatch validates the filters of Watch, which only uses the ids and
+// the ranges.
+func (f Filters) ValidateWatch() error {
+	verr := &ValidationError{}
+
+	if f.AgeGte != 0 && f.AgeLte != 0 && f.AgeGte > f.AgeLte {
+		verr.add("AgeGte", "must be less than or equal to AgeLte")
+	}
+
+	if !f.CreatedAtGte.IsZero() && !f.CreatedAtLte.IsZero() && f.CreatedAtGte.After(f.CreatedAtLte) {
+		verr.add("CreatedAtGte", "must be before CreatedAtLte")
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+// HasRanges reports whether the filters set a bound of the age or of
+// created_at.
+func (f Filters) HasRanges() bool {
+	return f.AgeGte != 0 || f.AgeLte != 0 || !f.CreatedAtGte.IsZero() || !f.CreatedAtLte.IsZero()
+}
+
+// Matches reports whether the event concerns the users selected by the
+// filters. Offset, Limit and OrderBy do not apply, nor the default
+// created_at window, the changes being recent. The ranges apply to user, as
+// it is after the change: the users missing by then and the deleted ones
+// are only matched on their ids, when the filters have no ranges.
+func (f Filters) Matches(event UserEvent, user *User) bool {
+	if len(f.IDs) > 0 {
+		found := false
+		for _, id := range f.IDs {
+			found = found || id == event.UserID
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if !f.HasRanges() {
+		return true
+	}
+
+	if event.Type == UserDeleted || user == nil {
+		return false
+	}
+
+	switch {
+	case f.AgeGte != 0 && user.Age < f.AgeGte, f.AgeLte != 0 && user.Age > f.AgeLte:
+		return false
+	case !f.CreatedAtGte.IsZero() && user.CreatedAt.Before(f.CreatedAtGte), !f.CreatedAtLte.IsZero() && user.CreatedAt.After(f.CreatedAtLte):
+		return false
+	}
+
+	return true
+}
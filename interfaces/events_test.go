@@ -0,0 +1,45 @@
+package interfaces_test
+
+import (
+	"testing"
+	"time"
+
+	"repos/interfaces"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiltersMatches(t *testing.T) {
+	user := &interfaces.User{ID: 1, Age: 30, CreatedAt: time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC)}
+	updated := interfaces.UserEvent{Type: interfaces.UserUpdated, UserID: 1}
+	deleted := interfaces.UserEvent{Type: interfaces.UserDeleted, UserID: 1}
+
+	tests := []struct {
+		name    string
+		filters interfaces.Filters
+		event   interfaces.UserEvent
+		user    *interfaces.User
+		want    bool
+	}{
+		{"no filters", interfaces.Filters{}, updated, user, true},
+		{"id", interfaces.Filters{IDs: []int64{2, 1}}, updated, user, true},
+		{"other id", interfaces.Filters{IDs: []int64{2}}, updated, user, false},
+		{"age in range", interfaces.Filters{AgeGte: 18, AgeLte: 30}, updated, user, true},
+		{"age out of range", interfaces.Filters{AgeGte: 31}, updated, user, false},
+		{"created before", interfaces.Filters{CreatedAtGte: time.Date(2024, 4, 11, 0, 0, 0, 0, time.UTC)}, updated, user, false},
+		{"deleted by id", interfaces.Filters{IDs: []int64{1}}, deleted, nil, true},
+		{"deleted with ranges", interfaces.Filters{AgeGte: 18}, deleted, nil, false},
+		{"missing user with ranges", interfaces.Filters{AgeGte: 18}, updated, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filters.Matches(tt.event, tt.user), "they should be equal")
+		})
+	}
+}
+
+func TestFiltersValidateWatch(t *testing.T) {
+	assert.NoError(t, interfaces.Filters{AgeGte: 18, Limit: -1}.ValidateWatch(), "should ignore the pagination")
+	assert.Error(t, interfaces.Filters{AgeGte: 30, AgeLte: 18}.ValidateWatch(), "should fail")
+}
@@ -0,0 +1,30 @@
+package interfaces
+
+import "repos/utils"
+
+const (
+	FieldID        = "id"
+	FieldName      = "name"
+	FieldAge       = "age"
+	FieldCreatedAt = "created_at"
+	FieldUpdatedAt = "updated_at"
+)
+
+// UserFields are the columns of User that can be selected with utils.WithFields.
+var UserFields = []string{FieldID, FieldName, FieldAge, FieldCreatedAt, FieldUpdatedAt}
+
+// UserEntity is the metadata the generic repositories store the users with.
+var UserEntity = Entity[User, int64]{
+	Table:      "users",
+	IDField:    FieldID,
+	ID:         func(u *User) int64 { return int64(u.ID) },
+	Fields:     UserFields,
+	Sortable:   []string{FieldCreatedAt, FieldAge, FieldName},
+	Filterable: []string{FieldCreatedAt, FieldAge},
+}
+
+// SelectedFields returns the fields requested with utils.WithFields, or nil
+// when every field must be loaded.
+func SelectedFields(opts ...utils.Options) ([]string, error) {
+	return UserEntity.SelectedFields(opts...)
+}
@@ -0,0 +1,37 @@
+package interfaces_test
+
+import (
+	"testing"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectedFields(t *testing.T) {
+	t.Run("all fields by default", func(t *testing.T) {
+		fields, err := interfaces.SelectedFields()
+
+		assert.Nil(t, err)
+		assert.Empty(t, fields, "they should be equal")
+	})
+
+	t.Run("known fields", func(t *testing.T) {
+		fields, err := interfaces.SelectedFields(utils.WithFields(interfaces.FieldID, interfaces.FieldName))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"id", "name"}, fields, "they should be equal")
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		_, err := interfaces.SelectedFields(utils.WithFields(interfaces.FieldID, "password", "email"))
+
+		verr, ok := err.(*interfaces.ValidationError)
+		if !ok {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+
+		assert.Equal(t, 2, len(verr.Fields), "they should be equal")
+	})
+}
@@ -0,0 +1,138 @@
+package interfaces
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"repos/utils"
+)
+
+// now is the reference date the default created_at window is computed from.
+var now = func() time.Time {
+	return time.Date(2024, 4, 17, 0, 0, 0, 0, time.Local)
+}
+
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError lists every filter field that failed validation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+
+	return "invalid filters: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (o OrderBy) IsValid() bool {
+	switch o {
+	case OrderByAge, OrderByName, OrderByCreatedAt:
+		return true
+	}
+
+	return false
+}
+
+// Validate reports every invalid field of the filters without applying defaults.
+func (f Filters) Validate() error {
+	_, err := f.Normalize()
+	return err
+}
+
+// Normalize validates the filters and returns a copy with the defaults applied:
+// limit falls back to utils.Limit, order to created_at, created_at_lte to the
+// reference date and created_at_gte to utils.MaxInterval before created_at_lte.
+func (f Filters) Normalize() (Filters, error) {
+	verr := &ValidationError{}
+
+	if f.Offset < 0 {
+		verr.add("Offset", "must be greater than or equal to 0")
+	}
+
+	switch {
+	case f.Limit < 0:
+		verr.add("Limit", "must be greater than or equal to 0")
+	case f.Limit > utils.MaxLimit:
+		verr.add("Limit", "must be less than or equal to %d", utils.MaxLimit)
+	case f.Limit == 0:
+		f.Limit = utils.Limit
+	}
+
+	if f.OrderBy == "" {
+		f.OrderBy = OrderByCreatedAt
+	} else if !f.OrderBy.IsValid() {
+		verr.add("OrderBy", "unknown value %q", f.OrderBy)
+	}
+
+	if f.AgeGte != 0 && f.AgeLte != 0 && f.AgeGte > f.AgeLte {
+		verr.add("AgeGte", "must be less than or equal to AgeLte")
+	}
+
+	if f.CreatedAtLte.IsZero() {
+		f.CreatedAtLte = now()
+	}
+
+	if f.CreatedAtGte.IsZero() {
+		f.CreatedAtGte = f.CreatedAtLte.Add(-utils.MaxInterval)
+	}
+
+	if f.CreatedAtGte.After(f.CreatedAtLte) {
+		verr.add("CreatedAtGte", "must be before CreatedAtLte")
+	} else if f.CreatedAtLte.Sub(f.CreatedAtGte) > utils.MaxInterval {
+		verr.add("CreatedAtGte", "interval with CreatedAtLte must not exceed %v", utils.MaxInterval)
+	}
+
+	if len(verr.Fields) > 0 {
+		return f, verr
+	}
+
+	return f, nil
+}
+
+// Query normalises the filters and translates them to the generic query of
+// UserEntity.
+func (f Filters) Query() (Query[int64], error) {
+	f, err := f.Normalize()
+	if err != nil {
+		return Query[int64]{}, err
+	}
+
+	ranges := []Range{{Field: FieldCreatedAt, Gte: f.CreatedAtGte, Lte: f.CreatedAtLte}}
+
+	age := Range{Field: FieldAge}
+	if f.AgeGte != 0 {
+		age.Gte = f.AgeGte
+	}
+
+	if f.AgeLte != 0 {
+		age.Lte = f.AgeLte
+	}
+
+	if age.Gte != nil || age.Lte != nil {
+		ranges = append(ranges, age)
+	}
+
+	return Query[int64]{
+		Offset:  f.Offset,
+		Limit:   f.Limit,
+		OrderBy: string(f.OrderBy),
+		Ranges:  ranges,
+		IDs:     f.IDs,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package interfaces_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiltersNormalize(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		filters, err := interfaces.Filters{}.Normalize()
+		if err != nil {
+			t.Fatalf("normalizing filters: %v", err)
+		}
+
+		assert.Equal(t, 0, filters.Offset, "they should be equal")
+		assert.Equal(t, utils.Limit, filters.Limit, "they should be equal")
+		assert.Equal(t, interfaces.OrderByCreatedAt, filters.OrderBy, "they should be equal")
+		assert.Equal(t, time.Date(2024, 4, 17, 0, 0, 0, 0, time.Local), filters.CreatedAtLte, "they should be equal")
+		assert.Equal(t, utils.MaxInterval, filters.CreatedAtLte.Sub(filters.CreatedAtGte), "they should be equal")
+	})
+
+	t.Run("gte derived from lte", func(t *testing.T) {
+		lte := time.Date(2024, 4, 13, 0, 0, 0, 0, time.Local)
+
+		filters, err := interfaces.Filters{CreatedAtLte: lte}.Normalize()
+		if err != nil {
+			t.Fatalf("normalizing filters: %v", err)
+		}
+
+		assert.Equal(t, lte.Add(-utils.MaxInterval), filters.CreatedAtGte, "they should be equal")
+	})
+
+	t.Run("valid filters are kept", func(t *testing.T) {
+		in := interfaces.Filters{
+			Offset:       5,
+			Limit:        utils.MaxLimit,
+			OrderBy:      interfaces.OrderByAge,
+			AgeGte:       20,
+			AgeLte:       20,
+			CreatedAtGte: time.Date(2024, 4, 13, 0, 0, 0, 0, time.Local),
+			CreatedAtLte: time.Date(2024, 4, 15, 0, 0, 0, 0, time.Local),
+		}
+
+		filters, err := in.Normalize()
+		if err != nil {
+			t.Fatalf("normalizing filters: %v", err)
+		}
+
+		assert.Equal(t, in, filters, "they should be equal")
+	})
+
+	t.Run("every invalid field is reported", func(t *testing.T) {
+		err := interfaces.Filters{
+			Offset:       -1,
+			Limit:        utils.MaxLimit + 1,
+			OrderBy:      "password",
+			AgeGte:       40,
+			AgeLte:       20,
+			CreatedAtGte: time.Date(2024, 4, 15, 0, 0, 0, 0, time.Local),
+			CreatedAtLte: time.Date(2024, 4, 13, 0, 0, 0, 0, time.Local),
+		}.Validate()
+
+		var verr *interfaces.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+
+		fields := []string{}
+		for _, f := range verr.Fields {
+			fields = append(fields, f.Field)
+		}
+
+		assert.Equal(t, []string{"Offset", "Limit", "OrderBy", "AgeGte", "CreatedAtGte"}, fields, "they should be equal")
+	})
+
+	t.Run("negative limit", func(t *testing.T) {
+		assert.Error(t, interfaces.Filters{Limit: -1}.Validate())
+	})
+
+	t.Run("interval too wide", func(t *testing.T) {
+		err := interfaces.Filters{
+			CreatedAtGte: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local),
+			CreatedAtLte: time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local),
+		}.Validate()
+
+		assert.Error(t, err)
+	})
+}
@@ -34,9 +34,29 @@ type Filters struct {
 }
 
 type UsersRepo interface {
-	GetById(context.Context, int64, ...utils.Options) (*User, error)
-	GetAll(context.Context, Filters, ...utils.Options) ([]*User, int64, error)
-	Create(context.Context, *User, ...utils.Options) error
-	Update(context.Context, *User, map[string]interface{}, ...utils.Options) error
-	Delete(context.Context, []int64, ...utils.Options) error
+	Repo[User, int64, Filters]
+	Stats(context.Context, Filters, StatsQuery, ...utils.Options) (*UserStats, error)
+}
+
+// Names of the UsersRepo methods, as reported by the decorators and the fakes.
+const (
+	MethodGetById  = "GetById"
+	MethodGetAll   = "GetAll"
+	MethodGetByIDs = "GetByIDs"
+	MethodCreate   = "Create"
+	MethodUpdate   = "Update"
+	MethodDelete   = "Delete"
+	MethodStats    = "Stats"
+)
+
+// Explainer is implemented by the repositories able to describe how the
+// database runs the query of GetAll.
+type Explainer interface {
+	ExplainGetAll(context.Context, Filters, ...utils.Options) (string, error)
+}
+
+// MaxIDer is implemented by the repositories able to report the highest user
+// id, the bound of the tools walking every user by id.
+type MaxIDer interface {
+	MaxID(context.Context) (int64, error)
 }
@@ -0,0 +1,130 @@
+package interfaces
+
+import (
+	"context"
+
+	"repos/utils"
+)
+
+// Repo is the repository of the entities T identified by ID, listed with the
+// filters F. The generic backends list with Query, UsersRepo instantiates it
+// with the Filters of the users.
+type Repo[T any, ID comparable, F any] interface {
+	GetById(context.Context, ID, ...utils.Options) (*T, error)
+	GetAll(context.Context, F, ...utils.Options) ([]*T, int64, error)
+	GetByIDs(context.Context, []ID, ...utils.Options) ([]*T, []ID, error)
+	Create(context.Context, *T, ...utils.Options) error
+	Update(context.Context, *T, map[string]interface{}, ...utils.Options) error
+	Delete(context.Context, []ID, ...utils.Options) error
+}
+
+// Entity describes how the generic repositories store T: the table, or
+// collection, holding it, the column of its id and the fields that can be
+// selected, sorted and filtered.
+type Entity[T any, ID comparable] struct {
+	Table   string
+	IDField string
+	// ID returns the id of an entity.
+	ID     func(*T) ID
+	Fields []string
+	// Sortable are the fields GetAll can order by, the first is the default.
+	Sortable   []string
+	Filterable []string
+}
+
+// Range restricts Field to the values between Gte and Lte, both included. A
+// nil bound is not applied.
+type Range struct {
+	Field string
+	Gte   interface{}
+	Lte   interface{}
+}
+
+// Query are the generic filters: ranges over the filterable fields of an
+// entity, ids, the order and the pagination. Lookups by ids return every
+// match, the pagination is ignored.
+type Query[ID comparable] struct {
+	Offset  int
+	Limit   int
+	OrderBy string
+	Ranges  []Range
+	IDs     []ID
+}
+
+// Normalize validates the query against the entity and returns a copy with
+// the defaults applied: limit falls back to utils.Limit and order to the
+// first sortable field.
+func (e Entity[T, ID]) Normalize(q Query[ID]) (Query[ID], error) {
+	verr := &ValidationError{}
+
+	if q.Offset < 0 {
+		verr.add("Offset", "must be greater than or equal to 0")
+	}
+
+	switch {
+	case q.Limit < 0:
+		verr.add("Limit", "must be greater than or equal to 0")
+	case q.Limit > utils.MaxLimit:
+		verr.add("Limit", "must be less than or equal to %d", utils.MaxLimit)
+	case q.Limit == 0:
+		q.Limit = utils.Limit
+	}
+
+	if q.OrderBy == "" && len(e.Sortable) > 0 {
+		q.OrderBy = e.Sortable[0]
+	} else if !contains(e.Sortable, q.OrderBy) {
+		verr.add("OrderBy", "unknown value %q", q.OrderBy)
+	}
+
+	for _, r := range q.Ranges {
+		if !contains(e.Filterable, r.Field) {
+			verr.add("Ranges", "field %q can not be filtered", r.Field)
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return q, verr
+	}
+
+	return q, nil
+}
+
+// SelectedFields returns the fields requested with utils.WithFields, or nil
+// when every field must be loaded.
+func (e Entity[T, ID]) SelectedFields(opts ...utils.Options) ([]string, error) {
+	fields := utils.Fields(opts...)
+
+	verr := &ValidationError{}
+	for _, field := range fields {
+		if !contains(e.Fields, field) {
+			verr.add("Fields", "unknown field %q", field)
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+
+	return fields, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Bound returns v as the bound of a Range, nil when it is the zero value so
+// the bound is not applied.
+func Bound[V comparable](v V) interface{} {
+	var zero V
+	if v == zero {
+		return nil
+	}
+
+	return v
+}
@@ -0,0 +1,62 @@
+package interfaces_test
+
+import (
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityNormalize(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		query, err := interfaces.UserEntity.Normalize(interfaces.Query[int64]{})
+		if err != nil {
+			t.Fatalf("normalizing query: %v", err)
+		}
+
+		assert.Equal(t, utils.Limit, query.Limit, "they should be equal")
+		assert.Equal(t, interfaces.FieldCreatedAt, query.OrderBy, "they should be equal")
+	})
+
+	t.Run("every invalid field is reported", func(t *testing.T) {
+		_, err := interfaces.UserEntity.Normalize(interfaces.Query[int64]{
+			Offset:  -1,
+			Limit:   utils.MaxLimit + 1,
+			OrderBy: "password",
+			Ranges:  []interfaces.Range{{Field: interfaces.FieldName, Gte: "a"}},
+		})
+
+		verr, ok := err.(*interfaces.ValidationError)
+		if !ok {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+
+		assert.Equal(t, 4, len(verr.Fields), "they should be equal")
+	})
+}
+
+func TestFiltersQuery(t *testing.T) {
+	lte := time.Date(2024, 4, 13, 0, 0, 0, 0, time.Local)
+
+	t.Run("ranges", func(t *testing.T) {
+		query, err := interfaces.Filters{CreatedAtLte: lte, AgeGte: 20, OrderBy: interfaces.OrderByAge}.Query()
+		if err != nil {
+			t.Fatalf("translating filters: %v", err)
+		}
+
+		assert.Equal(t, string(interfaces.OrderByAge), query.OrderBy, "they should be equal")
+		assert.Equal(t, []interfaces.Range{
+			{Field: interfaces.FieldCreatedAt, Gte: lte.Add(-utils.MaxInterval), Lte: lte},
+			{Field: interfaces.FieldAge, Gte: uint8(20)},
+		}, query.Ranges, "they should be equal")
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		_, err := interfaces.Filters{Limit: -1}.Query()
+
+		assert.Error(t, err)
+	})
+}
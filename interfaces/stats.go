@@ -0,0 +1,101 @@
+package interfaces
+
+import (
+	"fmt"
+	"time"
+)
+
+type Interval string
+
+const (
+	IntervalDay  Interval = "day"
+	IntervalWeek Interval = "week"
+)
+
+// DefaultAgeBoundaries split users into age buckets when a StatsQuery does
+// not define its own.
+var DefaultAgeBoundaries = []uint8{18, 25, 35, 45, 55, 65}
+
+type StatsQuery struct {
+	// AgeBoundaries are the ascending ages splitting the buckets, so
+	// {18, 65} yields [0, 18), [18, 65) and [65, 256).
+	AgeBoundaries []uint8
+	Interval      Interval
+}
+
+// Normalize validates the query and applies DefaultAgeBoundaries and IntervalDay.
+func (q StatsQuery) Normalize() (StatsQuery, error) {
+	verr := &ValidationError{}
+
+	if len(q.AgeBoundaries) == 0 {
+		q.AgeBoundaries = DefaultAgeBoundaries
+	}
+
+	if q.AgeBoundaries[0] == 0 {
+		verr.add("AgeBoundaries", "must be greater than 0")
+	}
+
+	for i := 1; i < len(q.AgeBoundaries); i++ {
+		if q.AgeBoundaries[i] <= q.AgeBoundaries[i-1] {
+			verr.add("AgeBoundaries", "must be strictly ascending")
+			break
+		}
+	}
+
+	switch q.Interval {
+	case "":
+		q.Interval = IntervalDay
+	case IntervalDay, IntervalWeek:
+	default:
+		verr.add("Interval", "unknown value %q", q.Interval)
+	}
+
+	if len(verr.Fields) > 0 {
+		return q, verr
+	}
+
+	return q, nil
+}
+
+// AgeBuckets returns the empty buckets delimited by AgeBoundaries, ready to be
+// filled by the repositories.
+func (q StatsQuery) AgeBuckets() []AgeBucket {
+	buckets := make([]AgeBucket, len(q.AgeBoundaries)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = int(q.AgeBoundaries[i-1])
+		}
+
+		buckets[i].Max = 256
+		if i < len(q.AgeBoundaries) {
+			buckets[i].Max = int(q.AgeBoundaries[i])
+		}
+	}
+
+	return buckets
+}
+
+// AgeBucket counts the users with Min <= age < Max.
+type AgeBucket struct {
+	Min   int
+	Max   int
+	Count int64
+}
+
+func (b AgeBucket) String() string {
+	return fmt.Sprintf("[%d, %d)", b.Min, b.Max)
+}
+
+// SignUps counts the users created in the period starting at Period, a day or
+// a week starting on Monday, in UTC whatever the time zone of the database.
+type SignUps struct {
+	Period time.Time
+	Count  int64
+}
+
+type UserStats struct {
+	Total      int64
+	AverageAge float64
+	AgeBuckets []AgeBucket
+	SignUps    []SignUps
+}
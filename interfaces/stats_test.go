@@ -0,0 +1,42 @@
+package interfaces_test
+
+import (
+	"testing"
+
+	"repos/interfaces"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsQueryNormalize(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		query, err := interfaces.StatsQuery{}.Normalize()
+		if err != nil {
+			t.Fatalf("normalizing query: %v", err)
+		}
+
+		assert.Equal(t, interfaces.IntervalDay, query.Interval, "they should be equal")
+		assert.Equal(t, interfaces.DefaultAgeBoundaries, query.AgeBoundaries, "they should be equal")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := interfaces.StatsQuery{AgeBoundaries: []uint8{30, 20}, Interval: "year"}.Normalize()
+
+		verr, ok := err.(*interfaces.ValidationError)
+		if !ok {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+
+		assert.Equal(t, 2, len(verr.Fields), "they should be equal")
+	})
+
+	t.Run("buckets", func(t *testing.T) {
+		buckets := interfaces.StatsQuery{AgeBoundaries: []uint8{18, 65}}.AgeBuckets()
+
+		assert.Equal(t, []interfaces.AgeBucket{
+			{Min: 0, Max: 18},
+			{Min: 18, Max: 65},
+			{Min: 65, Max: 256},
+		}, buckets, "they should be equal")
+	})
+}
@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"reflect"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// Filters matches the filters of a call normalising to the same filters as
+// want, so the expectations do not need to spell out the defaults.
+//
+//	repo.EXPECT().GetAll(mock.Anything, mocks.Filters(interfaces.Filters{AgeGte: 18})).Return(users, 2, nil)
+func Filters(want interfaces.Filters) interface{} {
+	return mock.MatchedBy(func(got interfaces.Filters) bool {
+		got, gotErr := got.Normalize()
+		want, wantErr := want.Normalize()
+
+		return (gotErr == nil) == (wantErr == nil) && reflect.DeepEqual(got, want)
+	})
+}
+
+// Option matches an option of a call configuring the statements like want.
+// Options are functions, testify can not compare them on its own.
+//
+//	repo.EXPECT().GetById(mock.Anything, int64(1), mocks.Option(utils.WithLock)).Return(user, nil)
+func Option(want utils.Options) interface{} {
+	return mock.MatchedBy(func(got utils.Options) bool {
+		return utils.EqualOptions([]utils.Options{got}, []utils.Options{want})
+	})
+}
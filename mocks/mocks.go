@@ -0,0 +1,6 @@
+// Package mocks holds the test doubles of the users repository: UsersRepo,
+// a mock generated by mockery whose expectations are set with EXPECT, and
+// RecordingUsersRepo, an in memory fake recording every call.
+package mocks
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.5 --name UsersRepo --dir ../interfaces --output . --outpkg mocks --with-expecter
@@ -0,0 +1,145 @@
+package mocks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUsersRepoExpectations(t *testing.T) {
+	ctx := context.Background()
+	repo := mocks.NewUsersRepo(t)
+
+	users := []*interfaces.User{{ID: 1, Name: "first"}}
+	repo.EXPECT().
+		GetAll(mock.Anything, mocks.Filters(interfaces.Filters{AgeGte: 18}), mocks.Option(utils.WithFields(interfaces.FieldName))).
+		Return(users, 1, nil).
+		Once()
+	repo.EXPECT().
+		GetById(mock.Anything, int64(2), mocks.Option(utils.WithLock)).
+		Return(nil, errors.New("not found")).
+		Once()
+
+	// the defaults of the expected filters are applied before comparing.
+	got, total, err := repo.GetAll(ctx, interfaces.Filters{AgeGte: 18, Limit: utils.Limit}, utils.WithFields(interfaces.FieldName))
+	assert.Nil(t, err, "should be nil")
+	assert.Equal(t, int64(1), total, "they should be equal")
+	assert.Equal(t, users, got, "they should be equal")
+
+	_, err = repo.GetById(ctx, 2, utils.WithLock)
+	assert.Error(t, err)
+}
+
+// matcher is implemented by the matchers of mock.MatchedBy.
+type matcher interface {
+	Matches(interface{}) bool
+}
+
+func TestMatchers(t *testing.T) {
+	t.Run("filters", func(t *testing.T) {
+		m := mocks.Filters(interfaces.Filters{OrderBy: interfaces.OrderByAge}).(matcher)
+
+		assert.True(t, m.Matches(interfaces.Filters{OrderBy: interfaces.OrderByAge, Limit: utils.Limit}))
+		assert.False(t, m.Matches(interfaces.Filters{OrderBy: interfaces.OrderByName}))
+		assert.False(t, m.Matches(interfaces.Filters{OrderBy: interfaces.OrderByAge, Limit: -1}))
+	})
+
+	t.Run("options", func(t *testing.T) {
+		m := mocks.Option(utils.WithFields(interfaces.FieldID)).(matcher)
+
+		assert.True(t, m.Matches(utils.WithFields(interfaces.FieldID)))
+		assert.False(t, m.Matches(utils.WithFields(interfaces.FieldName)))
+		assert.False(t, m.Matches(utils.WithLock))
+	})
+}
+
+func TestRecordingUsersRepo(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 4, 15, 0, 0, 0, 0, time.Local)
+
+	r := mocks.NewRecordingUsersRepo(
+		interfaces.User{ID: 1, Name: "first", Age: 20, CreatedAt: day},
+		interfaces.User{ID: 2, Name: "second", Age: 40, CreatedAt: day.Add(time.Hour)},
+	)
+
+	t.Run("records every call", func(t *testing.T) {
+		if _, _, err := r.GetAll(ctx, interfaces.Filters{}, utils.WithLock); err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		if err := r.Delete(ctx, []int64{3}); err != nil {
+			t.Fatalf("delete users: %v", err)
+		}
+
+		assert.Equal(t, []string{interfaces.MethodGetAll, interfaces.MethodDelete}, r.Methods(), "they should be equal")
+
+		calls := r.CallsTo(interfaces.MethodDelete)
+		assert.Equal(t, []interface{}{[]int64{3}}, calls[0].Args, "they should be equal")
+		assert.True(t, utils.EqualOptions(r.Calls()[0].Opts, []utils.Options{utils.WithLock}))
+
+		r.Reset()
+		assert.Empty(t, r.Calls(), "should be empty")
+	})
+
+	t.Run("filters and orders", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{AgeGte: 30})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, int64(1), total, "they should be equal")
+		assert.Equal(t, "second", users[0].Name, "they should be equal")
+
+		users, _, err = r.GetAll(ctx, interfaces.Filters{OrderBy: interfaces.OrderByName, Offset: 1})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, "first", users[0].Name, "they should be equal")
+	})
+
+	t.Run("writes", func(t *testing.T) {
+		user := &interfaces.User{Name: "third", Age: 30}
+		if err := r.Create(ctx, user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+
+		assert.Equal(t, uint(3), user.ID, "they should be equal")
+
+		if err := r.Update(ctx, user, map[string]interface{}{"age": 31}); err != nil {
+			t.Fatalf("update user: %v", err)
+		}
+
+		got, err := r.GetById(ctx, 3)
+		assert.Nil(t, err, "should be nil")
+		assert.Equal(t, uint8(31), got.Age, "they should be equal")
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{}, interfaces.StatsQuery{AgeBoundaries: []uint8{30}})
+		if err != nil {
+			t.Fatalf("get stats: %v", err)
+		}
+
+		assert.Equal(t, int64(2), stats.Total, "they should be equal")
+		assert.Equal(t, 30.0, stats.AverageAge, "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[1].Count, "they should be equal")
+	})
+
+	t.Run("failures are recorded", func(t *testing.T) {
+		r.Fail(errors.New("boom"))
+		defer r.Fail(nil)
+
+		_, err := r.GetById(ctx, 1)
+		assert.Error(t, err)
+		assert.Equal(t, interfaces.MethodGetById, r.Methods()[len(r.Methods())-1], "they should be equal")
+	})
+}
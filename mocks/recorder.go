@@ -0,0 +1,343 @@
+package mocks
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+)
+
+// Call is a call received by RecordingUsersRepo: the method, the arguments
+// between the context and the options, and the options.
+type Call struct {
+	Method string
+	Args   []interface{}
+	Opts   []utils.Options
+}
+
+// RecordingUsersRepo is an in memory UsersRepo recording every call, so the
+// tests of its consumers can assert which operations were performed. It
+// applies the filters like the databases do, Stats leaves SignUps empty.
+type RecordingUsersRepo struct {
+	mu    sync.Mutex
+	users map[int64]interfaces.User
+	calls []Call
+	err   error
+}
+
+func NewRecordingUsersRepo(users ...interfaces.User) *RecordingUsersRepo {
+	r := &RecordingUsersRepo{users: map[int64]interfaces.User{}}
+	for _, u := range users {
+		r.users[int64(u.ID)] = u
+	}
+
+	return r
+}
+
+// Fail makes the following calls return err after being recorded, nil
+// makes them succeed again.
+func (r *RecordingUsersRepo) Fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = err
+}
+
+// Calls returns the calls received so far, in order.
+func (r *RecordingUsersRepo) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call{}, r.calls...)
+}
+
+// Methods returns the methods called so far, in order.
+func (r *RecordingUsersRepo) Methods() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	methods := make([]string, len(r.calls))
+	for i, c := range r.calls {
+		methods[i] = c.Method
+	}
+
+	return methods
+}
+
+// CallsTo returns the calls received by method, in order.
+func (r *RecordingUsersRepo) CallsTo(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := []Call{}
+	for _, c := range r.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+// Reset forgets the calls received so far, the users are kept.
+func (r *RecordingUsersRepo) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+}
+
+// record saves the call and returns the error to fail it with. The lock is
+// held until unlock is called.
+func (r *RecordingUsersRepo) record(method string, opts []utils.Options, args ...interface{}) (unlock func(), err error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, Call{Method: method, Args: args, Opts: opts})
+
+	return r.mu.Unlock, r.err
+}
+
+func (r *RecordingUsersRepo) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	unlock, err := r.record(interfaces.MethodGetById, opts, id)
+	defer unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &u, nil
+}
+
+func (r *RecordingUsersRepo) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	unlock, err := r.record(interfaces.MethodGetAll, opts, filters)
+	defer unlock()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filters, err = filters.Normalize()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := r.filter(filters)
+
+	sort.SliceStable(users, func(i, j int) bool {
+		switch filters.OrderBy {
+		case interfaces.OrderByAge:
+			return users[i].Age > users[j].Age
+		case interfaces.OrderByName:
+			return users[i].Name > users[j].Name
+		}
+
+		return users[i].CreatedAt.After(users[j].CreatedAt)
+	})
+
+	total := int64(len(users))
+	if len(filters.IDs) > 0 {
+		return users, total, nil
+	}
+
+	start := min(filters.Offset, len(users))
+	end := min(start+filters.Limit, len(users))
+
+	return users[start:end], total, nil
+}
+
+// filter returns the users matching already normalised filters, by id.
+func (r *RecordingUsersRepo) filter(filters interfaces.Filters) []*interfaces.User {
+	ids := map[int64]bool{}
+	for _, id := range filters.IDs {
+		ids[id] = true
+	}
+
+	users := []*interfaces.User{}
+	for _, id := range r.sortedIDs() {
+		u := r.users[id]
+
+		switch {
+		case u.CreatedAt.Before(filters.CreatedAtGte), u.CreatedAt.After(filters.CreatedAtLte):
+			continue
+		case filters.AgeGte != 0 && u.Age < filters.AgeGte, filters.AgeLte != 0 && u.Age > filters.AgeLte:
+			continue
+		case len(ids) > 0 && !ids[id]:
+			continue
+		}
+
+		users = append(users, &u)
+	}
+
+	return users
+}
+
+func (r *RecordingUsersRepo) sortedIDs() []int64 {
+	ids := make([]int64, 0, len(r.users))
+	for id := range r.users {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func (r *RecordingUsersRepo) GetByIDs(ctx context.Context, ids []int64, opts ...utils.Options) ([]*interfaces.User, []int64, error) {
+	unlock, err := r.record(interfaces.MethodGetByIDs, opts, ids)
+	defer unlock()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users, missing := []*interfaces.User{}, []int64{}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		u, ok := r.users[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		users = append(users, &u)
+	}
+
+	return users, missing, nil
+}
+
+// Create stores a copy of the user, giving it the next id and the current
+// time as the databases do when they are not set.
+func (r *RecordingUsersRepo) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	unlock, err := r.record(interfaces.MethodCreate, opts, user)
+	defer unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if user.ID == 0 {
+		ids := r.sortedIDs()
+		user.ID = 1
+		if len(ids) > 0 {
+			user.ID = uint(ids[len(ids)-1] + 1)
+		}
+	}
+
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	r.users[int64(user.ID)] = *user
+
+	return nil
+}
+
+// Update applies the values of the known columns, the others are ignored.
+func (r *RecordingUsersRepo) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	unlock, err := r.record(interfaces.MethodUpdate, opts, user, vals)
+	defer unlock()
+
+	if err != nil {
+		return err
+	}
+
+	u, ok := r.users[int64(user.ID)]
+	if !ok {
+		return nil
+	}
+
+	for column, v := range vals {
+		field := reflect.ValueOf(&u).Elem().FieldByName(columns[column])
+		value := reflect.ValueOf(v)
+		if field.IsValid() && value.IsValid() && value.Type().ConvertibleTo(field.Type()) {
+			field.Set(value.Convert(field.Type()))
+		}
+	}
+
+	if _, ok := vals[interfaces.FieldUpdatedAt]; !ok {
+		u.UpdatedAt = time.Now()
+	}
+
+	r.users[int64(user.ID)] = u
+
+	return nil
+}
+
+// columns maps the columns of the users to the fields of User.
+var columns = map[string]string{
+	interfaces.FieldID:        "ID",
+	interfaces.FieldName:      "Name",
+	interfaces.FieldAge:       "Age",
+	interfaces.FieldCreatedAt: "CreatedAt",
+	interfaces.FieldUpdatedAt: "UpdatedAt",
+}
+
+func (r *RecordingUsersRepo) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	unlock, err := r.record(interfaces.MethodDelete, opts, ids)
+	defer unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		delete(r.users, id)
+	}
+
+	return nil
+}
+
+func (r *RecordingUsersRepo) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	unlock, err := r.record(interfaces.MethodStats, opts, filters, query)
+	defer unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err = filters.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	query, err = query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &interfaces.UserStats{AgeBuckets: query.AgeBuckets()}
+
+	var ages int
+	for _, u := range r.filter(filters) {
+		stats.Total++
+		ages += int(u.Age)
+
+		for i, b := range stats.AgeBuckets {
+			if int(u.Age) >= b.Min && int(u.Age) < b.Max {
+				stats.AgeBuckets[i].Count++
+			}
+		}
+	}
+
+	if stats.Total > 0 {
+		stats.AverageAge = float64(ages) / float64(stats.Total)
+	}
+
+	return stats, nil
+}
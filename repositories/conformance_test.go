@@ -0,0 +1,83 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+
+	"repos/interfaces"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRepoConformance checks the behaviour every repository shares, on a
+// backend holding none of the samples. It needs at least three samples with
+// distinct ids, missing must not be one of them and all must be filters
+// matching every sample.
+func testRepoConformance[T any, ID comparable, F any](t *testing.T, r interfaces.Repo[T, ID, F], entity interfaces.Entity[T, ID], samples []*T, missing ID, all F) {
+	ctx := context.Background()
+
+	ids := make([]ID, len(samples))
+	for i, s := range samples {
+		ids[i] = entity.ID(s)
+	}
+
+	idsOf := func(entities []*T) []ID {
+		found := make([]ID, len(entities))
+		for i, e := range entities {
+			found[i] = entity.ID(e)
+		}
+
+		return found
+	}
+
+	t.Run("create", func(t *testing.T) {
+		for _, s := range samples {
+			if err := r.Create(ctx, s); err != nil {
+				t.Fatalf("creating entity: %v", err)
+			}
+		}
+	})
+
+	t.Run("get by id", func(t *testing.T) {
+		e, err := r.GetById(ctx, ids[0])
+		if err != nil {
+			t.Fatalf("get entity: %v", err)
+		}
+
+		assert.Equal(t, ids[0], entity.ID(e), "they should be equal")
+	})
+
+	t.Run("get by ids", func(t *testing.T) {
+		entities, notFound, err := r.GetByIDs(ctx, []ID{ids[2], missing, ids[0]})
+		if err != nil {
+			t.Fatalf("get entities: %v", err)
+		}
+
+		assert.Equal(t, []ID{ids[2], ids[0]}, idsOf(entities), "they should be equal")
+		assert.Equal(t, []ID{missing}, notFound, "they should be equal")
+	})
+
+	t.Run("get all", func(t *testing.T) {
+		entities, total, err := r.GetAll(ctx, all)
+		if err != nil {
+			t.Fatalf("get entities: %v", err)
+		}
+
+		assert.Equal(t, int64(len(samples)), total, "they should be equal")
+		assert.ElementsMatch(t, ids, idsOf(entities), "they should be equal")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := r.Delete(ctx, ids[:1]); err != nil {
+			t.Fatalf("delete entity: %v", err)
+		}
+
+		entities, notFound, err := r.GetByIDs(ctx, ids)
+		if err != nil {
+			t.Fatalf("get entities: %v", err)
+		}
+
+		assert.Equal(t, ids[1:], idsOf(entities), "they should be equal")
+		assert.Equal(t, ids[:1], notFound, "they should be equal")
+	})
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormDialect holds how the gorm backends differ in the filtered queries.
+type gormDialect struct {
+	// date converts the dates of the filters to the values the columns are
+	// compared with.
+	date func(time.Time) time.Time
+}
+
+var (
+	mysqlDialect    = gormDialect{date: func(t time.Time) time.Time { return t }}
+	postgresDialect = gormDialect{date: func(t time.Time) time.Time { return t }}
+	// sqliteDialect compares the dates in UTC, SQLite stores them as text so
+	// only dates in the same zone sort like the times they stand for.
+	sqliteDialect = gormDialect{date: time.Time.UTC}
+)
+
+// value converts a bound of a range, only the dates depend on the dialect.
+func (d gormDialect) value(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return d.date(t)
+	}
+
+	return v
+}
+
+// gormFilters are the scopes compiled from a query. Where holds the
+// conditions shared by the count and the rows, Page the order and the
+// pagination. Filters is the normalised query they were compiled from.
+type gormFilters[ID comparable] struct {
+	Filters interfaces.Query[ID]
+	Where   func(*gorm.DB) *gorm.DB
+	Page    func(*gorm.DB) *gorm.DB
+	table   string
+}
+
+// compileFilters normalises the filters of the users and translates them to
+// gorm scopes, so every gorm backend applies the same defaults, ranges and
+// pagination.
+func compileFilters(dialect gormDialect, filters interfaces.Filters) (gormFilters[int64], error) {
+	query, err := filters.Query()
+	if err != nil {
+		return gormFilters[int64]{}, err
+	}
+
+	return compileQuery(dialect, interfaces.UserEntity, query)
+}
+
+// compileQuery normalises the query of an entity and translates it to gorm
+// scopes. The fields were validated against the entity, so they are safe to
+// write in the conditions.
+func compileQuery[T any, ID comparable](dialect gormDialect, entity interfaces.Entity[T, ID], query interfaces.Query[ID]) (gormFilters[ID], error) {
+	query, err := entity.Normalize(query)
+	if err != nil {
+		return gormFilters[ID]{}, err
+	}
+
+	where := func(db *gorm.DB) *gorm.DB {
+		for _, r := range query.Ranges {
+			if r.Gte != nil {
+				db = db.Where(r.Field+" >= ?", dialect.value(r.Gte))
+			}
+
+			if r.Lte != nil {
+				db = db.Where(r.Field+" <= ?", dialect.value(r.Lte))
+			}
+		}
+
+		if len(query.IDs) > 0 {
+			db = db.Where(entity.IDField+" IN ?", query.IDs)
+		}
+
+		return db
+	}
+
+	// lookups by ids return every match.
+	page := func(db *gorm.DB) *gorm.DB {
+		if len(query.IDs) > 0 {
+			return db
+		}
+
+		return db.
+			Limit(query.Limit).
+			Offset(query.Offset).
+			Order(clause.OrderByColumn{Column: clause.Column{Name: query.OrderBy}, Desc: true})
+	}
+
+	return gormFilters[ID]{Filters: query, Where: where, Page: page, table: entity.Table}, nil
+}
+
+// Query starts a statement on the table of the entity, configured by the
+// options and restricted by the where clauses.
+func (q gormFilters[ID]) Query(ctx context.Context, db *gorm.DB, opts ...utils.Options) *gorm.DB {
+	return utils.ConfigureDB(db, opts...).WithContext(ctx).Table(q.table).Scopes(q.Where)
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+// compiledSQL renders the query of the compiled filters without a database.
+func compiledSQL(t *testing.T, q gormFilters[int64]) (string, []interface{}) {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatalf("opening dry run db: %v", err)
+	}
+
+	var users []*interfaces.User
+	stmt := db.Scopes(q.Where, q.Page).Find(&users).Statement
+
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestCompileFilters(t *testing.T) {
+	lte := time.Date(2024, 4, 15, 0, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	gte := lte.Add(-24 * time.Hour)
+
+	t.Run("defaults", func(t *testing.T) {
+		q, err := compileFilters(mysqlDialect, interfaces.Filters{})
+		if err != nil {
+			t.Fatalf("compiling filters: %v", err)
+		}
+
+		sql, vars := compiledSQL(t, q)
+
+		assert.Equal(t, "SELECT * FROM `users` WHERE created_at >= ? AND created_at <= ? ORDER BY `created_at` DESC LIMIT ?", sql, "they should be equal")
+		assert.Equal(t, vars[1].(time.Time).Add(-utils.MaxInterval), vars[0], "they should be equal")
+		assert.Equal(t, utils.Limit, vars[2], "they should be equal")
+	})
+
+	t.Run("ranges and pagination", func(t *testing.T) {
+		q, err := compileFilters(mysqlDialect, interfaces.Filters{
+			Offset:       10,
+			Limit:        5,
+			OrderBy:      interfaces.OrderByAge,
+			AgeGte:       20,
+			AgeLte:       30,
+			CreatedAtGte: gte,
+			CreatedAtLte: lte,
+		})
+		if err != nil {
+			t.Fatalf("compiling filters: %v", err)
+		}
+
+		sql, vars := compiledSQL(t, q)
+
+		assert.Equal(t, "SELECT * FROM `users` WHERE created_at >= ? AND created_at <= ? AND age >= ? AND age <= ? ORDER BY `age` DESC LIMIT ? OFFSET ?", sql, "they should be equal")
+		assert.Equal(t, []interface{}{gte, lte, uint8(20), uint8(30), 5, 10}, vars, "they should be equal")
+	})
+
+	t.Run("ids are not paginated", func(t *testing.T) {
+		q, err := compileFilters(postgresDialect, interfaces.Filters{IDs: []int64{3, 4}, Limit: 1})
+		if err != nil {
+			t.Fatalf("compiling filters: %v", err)
+		}
+
+		sql, vars := compiledSQL(t, q)
+
+		assert.Equal(t, "SELECT * FROM `users` WHERE created_at >= ? AND created_at <= ? AND id IN (?,?)", sql, "they should be equal")
+		assert.Equal(t, []interface{}{int64(3), int64(4)}, vars[2:], "they should be equal")
+	})
+
+	t.Run("sqlite compares dates in UTC", func(t *testing.T) {
+		q, err := compileFilters(sqliteDialect, interfaces.Filters{CreatedAtGte: gte, CreatedAtLte: lte})
+		if err != nil {
+			t.Fatalf("compiling filters: %v", err)
+		}
+
+		_, vars := compiledSQL(t, q)
+
+		assert.Equal(t, lte.UTC(), vars[1], "they should be equal")
+		assert.Equal(t, time.UTC, vars[0].(time.Time).Location(), "they should be equal")
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		_, err := compileFilters(mysqlDialect, interfaces.Filters{Limit: -1})
+
+		assert.Error(t, err)
+	})
+}
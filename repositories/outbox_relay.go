@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Publisher hands the messages of the outbox to the other services, a
+// message broker usually. A message may be published more than once, the
+// consumers discard the sequences they already handled.
+type Publisher interface {
+	Publish(ctx context.Context, message OutboxMessage) error
+}
+
+// PublisherFunc adapts a function to a Publisher.
+type PublisherFunc func(ctx context.Context, message OutboxMessage) error
+
+func (f PublisherFunc) Publish(ctx context.Context, message OutboxMessage) error {
+	return f(ctx, message)
+}
+
+// relayPurgeInterval is how often Run purges the published messages.
+const relayPurgeInterval = 10 * time.Minute
+
+type relayConfig struct {
+	interval  time.Duration
+	batch     int
+	retention time.Duration
+	now       func() time.Time
+}
+
+type RelayOption func(*relayConfig)
+
+func defaultRelayConfig() relayConfig {
+	return relayConfig{
+		interval:  time.Second,
+		batch:     100,
+		retention: 7 * 24 * time.Hour,
+		now:       time.Now,
+	}
+}
+
+// WithRelayInterval sets how long Run waits between polls finding nothing
+// to publish.
+func WithRelayInterval(interval time.Duration) RelayOption {
+	return func(c *relayConfig) {
+		c.interval = interval
+	}
+}
+
+// WithRelayBatch sets how many messages a poll reads from the outbox.
+func WithRelayBatch(batch int) RelayOption {
+	return func(c *relayConfig) {
+		c.batch = batch
+	}
+}
+
+// WithRelayRetention sets how long the published messages are kept before
+// being purged, for the watchers of the outbox resuming late.
+func WithRelayRetention(retention time.Duration) RelayOption {
+	return func(c *relayConfig) {
+		c.retention = retention
+	}
+}
+
+// Relay publishes the messages of an outbox. Messages are only marked as
+// published once the publisher accepted them, a crash in between publishes
+// them again: the delivery is at least once. The messages of a user are
+// published in sequence order, after a failure the following ones of the
+// user wait for the next poll while those of the other users are published.
+// A single relay must run per outbox.
+type Relay struct {
+	store     OutboxStore
+	publisher Publisher
+	config    relayConfig
+}
+
+func NewRelay(store OutboxStore, publisher Publisher, opts ...RelayOption) *Relay {
+	config := defaultRelayConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	return &Relay{store: store, publisher: publisher, config: config}
+}
+
+// Run polls the outbox until ctx is done, right away while there are
+// messages left and every interval otherwise, and purges it every
+// relayPurgeInterval. The errors are logged.
+func (r *Relay) Run(ctx context.Context) {
+	var purged time.Time
+	for {
+		published, err := r.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "relaying outbox", slog.Any("error", err))
+		}
+
+		if time.Since(purged) >= relayPurgeInterval {
+			purged = time.Now()
+			if _, err := r.Purge(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "relaying outbox", slog.Any("error", err))
+			}
+		}
+
+		wait := r.config.interval
+		if published >= r.config.batch {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Poll publishes a batch of pending messages and returns how many were
+// published. When users fail, the messages behind theirs are read again
+// without them, so a failing user never holds back the others. The error
+// joins the failures of the publisher.
+func (r *Relay) Poll(ctx context.Context) (int, error) {
+	var (
+		published int
+		failed    = map[int64]bool{}
+		errs      []error
+	)
+	for {
+		exclude := make([]int64, 0, len(failed))
+		for id := range failed {
+			exclude = append(exclude, id)
+		}
+
+		messages, err := r.store.Pending(ctx, r.config.batch, exclude...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading outbox: %w", err))
+			break
+		}
+
+		var sequences []int64
+		for _, m := range messages {
+			if failed[m.UserID] {
+				continue
+			}
+
+			if err := r.publisher.Publish(ctx, m); err != nil {
+				failed[m.UserID] = true
+				errs = append(errs, fmt.Errorf("publishing message %d: %w", m.Sequence, err))
+				continue
+			}
+
+			sequences = append(sequences, m.Sequence)
+		}
+
+		if err := r.store.MarkPublished(ctx, sequences, r.config.now()); err != nil {
+			errs = append(errs, fmt.Errorf("marking messages published: %w", err))
+			break
+		}
+		published += len(sequences)
+
+		if len(messages) < r.config.batch || len(exclude) == len(failed) {
+			break
+		}
+	}
+
+	return published, errors.Join(errs...)
+}
+
+// Purge deletes the messages published longer ago than the retention and
+// returns how many.
+func (r *Relay) Purge(ctx context.Context) (int64, error) {
+	purged, err := r.store.Purge(ctx, r.config.now().Add(-r.config.retention))
+	if err != nil {
+		return 0, fmt.Errorf("purging outbox: %w", err)
+	}
+
+	return purged, nil
+}
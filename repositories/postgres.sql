@@ -0,0 +1,7 @@
+CREATE TABLE users (
+  id BIGSERIAL PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  age SMALLINT NOT NULL CHECK (age BETWEEN 0 AND 255),
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
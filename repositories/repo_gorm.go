@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormRepo is the repository of any entity stored by gorm. The users
+// repositories of MySQL, PostgreSQL and SQLite embed it, only adding what
+// is specific to the users.
+type gormRepo[T any, ID comparable] struct {
+	db      *gorm.DB
+	dialect gormDialect
+	entity  interfaces.Entity[T, ID]
+}
+
+// NewRepoMysql returns the repository of the entities T stored in the MySQL
+// table described by entity.
+func NewRepoMysql[T any, ID comparable](db *gorm.DB, entity interfaces.Entity[T, ID]) interfaces.Repo[T, ID, interfaces.Query[ID]] {
+	return &gormRepo[T, ID]{db: db, dialect: mysqlDialect, entity: entity}
+}
+
+// statement starts a statement on the table of the entity configured by the options.
+func (r gormRepo[T, ID]) statement(ctx context.Context, opts ...utils.Options) *gorm.DB {
+	return utils.ConfigureDB(r.db, opts...).WithContext(ctx).Table(r.entity.Table)
+}
+
+// writeStatement starts a write on the table of the entity, ignoring WithFields.
+func (r gormRepo[T, ID]) writeStatement(ctx context.Context, opts ...utils.Options) *gorm.DB {
+	return utils.ConfigureWriteDB(r.db, opts...).WithContext(ctx).Table(r.entity.Table)
+}
+
+func (r gormRepo[T, ID]) GetById(ctx context.Context, id ID, opts ...utils.Options) (*T, error) {
+	if _, err := r.entity.SelectedFields(opts...); err != nil {
+		return nil, err
+	}
+
+	var entity *T
+	err := r.statement(ctx, opts...).
+		Where(clause.Eq{Column: clause.Column{Name: r.entity.IDField}, Value: id}).
+		Find(&entity).
+		Error
+	return entity, err
+}
+
+func (r gormRepo[T, ID]) GetAll(ctx context.Context, query interfaces.Query[ID], opts ...utils.Options) ([]*T, int64, error) {
+	var entities []*T
+
+	q, err := compileQuery(r.dialect, r.entity, query)
+	if err != nil {
+		return entities, 0, err
+	}
+
+	if _, err := r.entity.SelectedFields(opts...); err != nil {
+		return entities, 0, err
+	}
+
+	stmp := q.Query(ctx, r.db, opts...)
+
+	var total = int64(len(q.Filters.IDs))
+	if len(q.Filters.IDs) > 0 {
+		err = stmp.Scopes(q.Page).Find(&entities).Error
+
+		return entities, total, err
+	}
+
+	if err := stmp.Model(new(T)).Count(&total).Error; err != nil {
+		return entities, 0, err
+	}
+
+	err = stmp.Scopes(q.Page).Find(&entities).Error
+
+	return entities, total, err
+}
+
+func (r gormRepo[T, ID]) GetByIDs(ctx context.Context, ids []ID, opts ...utils.Options) ([]*T, []ID, error) {
+	opts, err := withEntityIDField(r.entity, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entities []*T
+	for _, chunk := range chunkIDs(ids) {
+		var found []*T
+		if err := r.statement(ctx, opts...).Where(r.entity.IDField+" IN ?", chunk).Find(&found).Error; err != nil {
+			return nil, nil, err
+		}
+
+		entities = append(entities, found...)
+	}
+
+	entities, missing := sortEntities(r.entity, ids, entities)
+
+	return entities, missing, nil
+}
+
+func (r gormRepo[T, ID]) Create(ctx context.Context, entity *T, opts ...utils.Options) error {
+	return r.writeStatement(ctx, opts...).Create(entity).Error
+}
+
+func (r gormRepo[T, ID]) Update(ctx context.Context, entity *T, vals map[string]interface{}, opts ...utils.Options) error {
+	return r.writeStatement(ctx, opts...).Model(entity).Updates(vals).Error
+}
+
+func (r gormRepo[T, ID]) Delete(ctx context.Context, ids []ID, opts ...utils.Options) error {
+	return r.writeStatement(ctx, opts...).Where(r.entity.IDField+" IN ?", ids).Delete(new(T)).Error
+}
@@ -0,0 +1,132 @@
+package repositories_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"repos/interfaces"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// team is an entity with a string id, stored by the generic repositories
+// without any code of its own.
+type team struct {
+	Code    string `gorm:"primaryKey"`
+	Name    string
+	Members int
+}
+
+var teamEntity = interfaces.Entity[team, string]{
+	Table:      "teams",
+	IDField:    "code",
+	ID:         func(t *team) string { return t.Code },
+	Fields:     []string{"code", "name", "members"},
+	Sortable:   []string{"members", "name"},
+	Filterable: []string{"members"},
+}
+
+// openTestTeams opens a database file in a temporary directory with an empty teams table.
+func openTestTeams(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "teams.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&team{}); err != nil {
+		t.Fatalf("creating team table: %v", err)
+	}
+
+	return db
+}
+
+func newTestTeams(ctx context.Context, t *testing.T) interfaces.Repo[team, string, interfaces.Query[string]] {
+	r := repositories.NewRepoMysql(openTestTeams(t), teamEntity)
+	for _, tm := range []team{{"red", "Red", 3}, {"blue", "Blue", 5}, {"green", "Green", 8}} {
+		tm := tm
+		if err := r.Create(ctx, &tm); err != nil {
+			t.Fatalf("seeding teams: %v", err)
+		}
+	}
+
+	return r
+}
+
+func TestRepoGorm(t *testing.T) {
+	ctx := context.Background()
+	r := newTestTeams(ctx, t)
+
+	t.Run("get by id", func(t *testing.T) {
+		tm, err := r.GetById(ctx, "blue")
+		if err != nil {
+			t.Fatalf("get team: %v", err)
+		}
+
+		assert.Equal(t, "Blue", tm.Name, "they should be equal")
+	})
+
+	t.Run("get all", func(t *testing.T) {
+		teams, total, err := r.GetAll(ctx, interfaces.Query[string]{
+			Limit:  1,
+			Ranges: []interfaces.Range{{Field: "members", Gte: 4}},
+		})
+		if err != nil {
+			t.Fatalf("get teams: %v", err)
+		}
+
+		assert.Equal(t, int64(2), total, "they should be equal")
+		assert.Equal(t, 1, len(teams), "they should be equal")
+		assert.Equal(t, "green", teams[0].Code, "they should be equal")
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		_, _, err := r.GetAll(ctx, interfaces.Query[string]{OrderBy: "code"})
+		assert.Error(t, err)
+
+		_, err = r.GetById(ctx, "red", utils.WithFields("password"))
+		assert.Error(t, err)
+	})
+
+	t.Run("get by ids", func(t *testing.T) {
+		teams, missing, err := r.GetByIDs(ctx, []string{"green", "black", "red"}, utils.WithFields("name"))
+		if err != nil {
+			t.Fatalf("get teams: %v", err)
+		}
+
+		assert.Equal(t, []string{"black"}, missing, "they should be equal")
+		assert.Equal(t, "green", teams[0].Code, "they should be equal")
+		assert.Equal(t, "Red", teams[1].Name, "they should be equal")
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		if err := r.Update(ctx, &team{Code: "red"}, map[string]interface{}{"members": 4}); err != nil {
+			t.Fatalf("update team: %v", err)
+		}
+
+		if err := r.Delete(ctx, []string{"blue"}); err != nil {
+			t.Fatalf("delete team: %v", err)
+		}
+
+		teams, _, err := r.GetByIDs(ctx, []string{"red", "blue"})
+		if err != nil {
+			t.Fatalf("get teams: %v", err)
+		}
+
+		assert.Equal(t, 1, len(teams), "they should be equal")
+		assert.Equal(t, 4, teams[0].Members, "they should be equal")
+	})
+}
+
+func TestRepoGormConformance(t *testing.T) {
+	samples := []*team{{"red", "Red", 3}, {"blue", "Blue", 5}, {"green", "Green", 8}}
+
+	testRepoConformance(t, repositories.NewRepoMysql(openTestTeams(t), teamEntity), teamEntity, samples, "black", interfaces.Query[string]{})
+}
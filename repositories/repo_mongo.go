@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"context"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoRepo is the repository of any entity stored in a collection with the
+// default bson encoding, the fields are mapped to their keys by mongoField.
+// userRepoMongo embeds it, only adding what is specific to the users.
+type mongoRepo[T any, ID comparable] struct {
+	collection *mongo.Collection
+	entity     interfaces.Entity[T, ID]
+}
+
+// NewRepoMongo returns the repository of the entities T stored in the
+// collection described by entity.
+func NewRepoMongo[T any, ID comparable](db *mongo.Database, entity interfaces.Entity[T, ID]) interfaces.Repo[T, ID, interfaces.Query[ID]] {
+	return &mongoRepo[T, ID]{collection: db.Collection(entity.Table), entity: entity}
+}
+
+func (r mongoRepo[T, ID]) GetById(ctx context.Context, id ID, opts ...utils.Options) (*T, error) {
+	fields, err := r.entity.SelectedFields(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	findOptions := options.FindOne()
+	if len(fields) > 0 {
+		findOptions.SetProjection(mongoProjection(fields))
+	}
+
+	var entity *T
+	if err := r.collection.FindOne(ctx, bson.D{{mongoField(r.entity.IDField), id}}, findOptions).Decode(&entity); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (r mongoRepo[T, ID]) GetAll(ctx context.Context, query interfaces.Query[ID], opts ...utils.Options) ([]*T, int64, error) {
+	query, err := r.entity.Normalize(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fields, err := r.entity.SelectedFields(opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := r.filter(query)
+
+	var total = int64(len(query.IDs))
+	if len(query.IDs) == 0 {
+		if total, err = r.collection.CountDocuments(ctx, filter); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, r.findOptions(query, fields))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, 0, nil
+		}
+
+		return nil, 0, err
+	}
+
+	var entities []*T
+	if err = cursor.All(ctx, &entities); err != nil {
+		return nil, 0, err
+	}
+
+	return entities, total, nil
+}
+
+// filter builds the query document of an already normalised query.
+func (r mongoRepo[T, ID]) filter(query interfaces.Query[ID]) bson.D {
+	f := bson.A{}
+
+	for _, rg := range query.Ranges {
+		if rg.Gte != nil {
+			f = append(f, bson.D{{mongoField(rg.Field), bson.D{{"$gte", rg.Gte}}}})
+		}
+
+		if rg.Lte != nil {
+			f = append(f, bson.D{{mongoField(rg.Field), bson.D{{"$lte", rg.Lte}}}})
+		}
+	}
+
+	if len(query.IDs) > 0 {
+		f = append(f, bson.D{{mongoField(r.entity.IDField), bson.D{{"$in", query.IDs}}}})
+	}
+
+	if len(f) == 0 {
+		return bson.D{}
+	}
+
+	return bson.D{{"$and", f}}
+}
+
+// findOptions sorts, paginates and projects the find of an already
+// normalised query. Lookups by ids return every match.
+func (r mongoRepo[T, ID]) findOptions(query interfaces.Query[ID], fields []string) *options.FindOptions {
+	findOptions := options.Find()
+
+	if len(query.IDs) == 0 {
+		findOptions.
+			SetSkip(int64(query.Offset)).
+			SetLimit(int64(query.Limit)).
+			SetSort(bson.D{{mongoField(query.OrderBy), -1}})
+	}
+
+	if len(fields) > 0 {
+		findOptions.SetProjection(mongoProjection(fields))
+	}
+
+	return findOptions
+}
+
+func (r mongoRepo[T, ID]) GetByIDs(ctx context.Context, ids []ID, opts ...utils.Options) ([]*T, []ID, error) {
+	opts, err := withEntityIDField(r.entity, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	findOptions := options.Find()
+	if fields := utils.Fields(opts...); len(fields) > 0 {
+		findOptions.SetProjection(mongoProjection(fields))
+	}
+
+	var entities []*T
+	for _, chunk := range chunkIDs(ids) {
+		cursor, err := r.collection.Find(ctx, bson.M{mongoField(r.entity.IDField): bson.M{"$in": chunk}}, findOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var found []*T
+		if err = cursor.All(ctx, &found); err != nil {
+			return nil, nil, err
+		}
+
+		entities = append(entities, found...)
+	}
+
+	entities, missing := sortEntities(r.entity, ids, entities)
+
+	return entities, missing, nil
+}
+
+func (r mongoRepo[T, ID]) Create(ctx context.Context, entity *T, opts ...utils.Options) error {
+	_, err := r.collection.InsertOne(ctx, entity)
+
+	return err
+}
+
+func (r mongoRepo[T, ID]) Update(ctx context.Context, entity *T, vals map[string]interface{}, opts ...utils.Options) error {
+	updates := bson.D{}
+	for k, v := range vals {
+		update := bson.E{Key: mongoField(k), Value: v}
+		updates = append(updates, update)
+	}
+
+	updateFilter := bson.D{{"$set", updates}}
+
+	if _, err := r.collection.UpdateOne(ctx, bson.D{{mongoField(r.entity.IDField), r.entity.ID(entity)}}, updateFilter); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r mongoRepo[T, ID]) Delete(ctx context.Context, ids []ID, opts ...utils.Options) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{mongoField(r.entity.IDField): bson.M{"$in": ids}})
+
+	return err
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// Transactor runs functions in a transaction of the database of the
+// repositories, so what they write is committed with the change of a user.
+type Transactor interface {
+	// Transaction runs fn in a new transaction, or in the one ctx or opts
+	// already carry. The repository calls made with the context and the
+	// options given to fn join it.
+	Transaction(ctx context.Context, opts []utils.Options, fn func(context.Context, []utils.Options) error) error
+}
+
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+func (t gormTransactor) Transaction(ctx context.Context, opts []utils.Options, fn func(context.Context, []utils.Options) error) error {
+	if utils.HasTx(opts...) {
+		return fn(ctx, opts)
+	}
+
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(ctx, append(opts[:len(opts):len(opts)], utils.WithTx(tx)))
+	})
+}
+
+// statement returns the transaction of opts, or the database without one.
+func (t gormTransactor) statement(ctx context.Context, opts []utils.Options) *gorm.DB {
+	if tx := utils.Tx(opts...); tx != nil {
+		return tx.WithContext(ctx)
+	}
+
+	return t.db.WithContext(ctx)
+}
+
+// mongoTransactor runs the transactions of a client, whose server must be a
+// replica set. The repository calls join them through the session carried
+// by the context.
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+// Transaction retries the transaction as a whole on transient errors, fn
+// may run more than once.
+func (t mongoTransactor) Transaction(ctx context.Context, opts []utils.Options, fn func(context.Context, []utils.Options) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx, opts)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx, opts)
+	})
+
+	return err
+}
+
+// inTransaction reports whether the calls made with ctx and opts run in a
+// transaction, a gorm one given by opts or a Mongo session carried by ctx.
+func inTransaction(ctx context.Context, opts []utils.Options) bool {
+	return utils.HasTx(opts...) || mongo.SessionFromContext(ctx) != nil
+}
+
+// outsideTransaction returns ctx and opts without the transaction and the
+// lock they carry, for the calls that must not join them: the Mongo session
+// of ctx is hidden and the options end with utils.WithoutTx.
+func outsideTransaction(ctx context.Context, opts []utils.Options) (context.Context, []utils.Options) {
+	if mongo.SessionFromContext(ctx) != nil {
+		ctx = mongo.NewSessionContext(ctx, nil)
+	}
+
+	return ctx, withoutTx(opts)
+}
+
+// withoutTx returns a copy of opts ending with utils.WithoutTx, opts is left
+// untouched for the calls that still join the transaction.
+func withoutTx(opts []utils.Options) []utils.Options {
+	return append(opts[:len(opts):len(opts)], utils.WithoutTx)
+}
+
+// lockedRead returns the options reading every column of the users, locked
+// until the end of the transaction of opts.
+func lockedRead(opts []utils.Options) []utils.Options {
+	return append(opts[:len(opts):len(opts)], utils.WithLock, utils.WithFields(interfaces.UserFields...))
+}
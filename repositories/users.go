@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"repos/interfaces"
+	"repos/utils"
+)
+
+// Names of the UsersRepo methods, as reported by the decorators.
+const (
+	MethodGetById  = interfaces.MethodGetById
+	MethodGetAll   = interfaces.MethodGetAll
+	MethodGetByIDs = interfaces.MethodGetByIDs
+	MethodCreate   = interfaces.MethodCreate
+	MethodUpdate   = interfaces.MethodUpdate
+	MethodDelete   = interfaces.MethodDelete
+	MethodStats    = interfaces.MethodStats
+)
+
+// chunkIDs splits the distinct ids in chunks of at most utils.IDsChunk,
+// keeping every query below the placeholder and document size limits.
+func chunkIDs[ID comparable](ids []ID) [][]ID {
+	seen := make(map[ID]struct{}, len(ids))
+	chunks := [][]ID{}
+	chunk := []ID{}
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		chunk = append(chunk, id)
+		if len(chunk) == utils.IDsChunk {
+			chunks = append(chunks, chunk)
+			chunk = []ID{}
+		}
+	}
+
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// sortByIDs returns the users in the order of ids together with the ids that
+// were not found. Repeated ids are only returned once.
+func sortByIDs(ids []int64, users []*interfaces.User) ([]*interfaces.User, []int64) {
+	return sortEntities(interfaces.UserEntity, ids, users)
+}
+
+// sortEntities is sortByIDs for the entities of any generic repository.
+func sortEntities[T any, ID comparable](entity interfaces.Entity[T, ID], ids []ID, entities []*T) ([]*T, []ID) {
+	byID := make(map[ID]*T, len(entities))
+	for _, e := range entities {
+		byID[entity.ID(e)] = e
+	}
+
+	sorted := make([]*T, 0, len(entities))
+	missing := []ID{}
+	seen := make(map[ID]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		e, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		sorted = append(sorted, e)
+	}
+
+	return sorted, missing
+}
+
+// withIDField makes sure the id is loaded when the caller restricted the
+// fields, since it is needed to match the users with the requested ids.
+func withIDField(opts []utils.Options) ([]utils.Options, error) {
+	return withEntityIDField(interfaces.UserEntity, opts)
+}
+
+// withEntityIDField is withIDField for the entities of any generic repository.
+func withEntityIDField[T any, ID comparable](entity interfaces.Entity[T, ID], opts []utils.Options) ([]utils.Options, error) {
+	fields, err := entity.SelectedFields(opts...)
+	if err != nil || len(fields) == 0 {
+		return opts, err
+	}
+
+	for _, f := range fields {
+		if f == entity.IDField {
+			return opts, nil
+		}
+	}
+
+	fields = append([]string{entity.IDField}, fields...)
+
+	return append(opts, utils.WithFields(fields...)), nil
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// AuditRecord describes a change made to a user: who made it, with which
+// method, and the values of the changed columns before and after it. Before
+// is empty for Create and After for Delete.
+type AuditRecord struct {
+	ID        uint64                 `gorm:"primaryKey" bson:"-"`
+	Actor     string                 `gorm:"size:255;not null"`
+	Operation string                 `gorm:"size:16;not null"`
+	UserID    int64                  `gorm:"not null;index"`
+	Before    map[string]interface{} `gorm:"type:text;serializer:json"`
+	After     map[string]interface{} `gorm:"type:text;serializer:json"`
+	CreatedAt time.Time              `gorm:"not null"`
+}
+
+func (AuditRecord) TableName() string {
+	return "audit"
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose changes are audited as made by actor,
+// usually the authenticated user of the request.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor given with WithActor, empty without one.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+
+	return actor
+}
+
+// AuditStore writes the audit records in the transaction of the changes they
+// describe.
+type AuditStore interface {
+	Transactor
+	// Write inserts the records in the transaction of ctx and opts.
+	Write(ctx context.Context, opts []utils.Options, records ...AuditRecord) error
+}
+
+type auditStoreGorm struct {
+	gormTransactor
+}
+
+// NewAuditStoreMysql returns the store of the audit table, in the database
+// of the gorm repositories, MySQL, PostgreSQL or SQLite. The table is
+// created by MigrateAudit.
+func NewAuditStoreMysql(db *gorm.DB) AuditStore {
+	return auditStoreGorm{gormTransactor{db: db}}
+}
+
+// MigrateAudit creates or updates the audit table.
+func MigrateAudit(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).AutoMigrate(&AuditRecord{})
+}
+
+func (s auditStoreGorm) Write(ctx context.Context, opts []utils.Options, records ...AuditRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return s.statement(ctx, opts).Create(&records).Error
+}
+
+type auditStoreMongo struct {
+	mongoTransactor
+	collection *mongo.Collection
+}
+
+// NewAuditStoreMongo returns the store of the audit collection of db, which
+// must belong to a replica set for the transactions.
+func NewAuditStoreMongo(db *mongo.Database) AuditStore {
+	return auditStoreMongo{mongoTransactor{client: db.Client()}, db.Collection("audit")}
+}
+
+func (s auditStoreMongo) Write(ctx context.Context, opts []utils.Options, records ...AuditRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(records))
+	for i, r := range records {
+		docs[i] = r
+	}
+
+	_, err := s.collection.InsertMany(ctx, docs)
+
+	return err
+}
+
+type auditConfig struct {
+	now func() time.Time
+}
+
+type AuditOption func(*auditConfig)
+
+func defaultAuditConfig() auditConfig {
+	return auditConfig{now: time.Now}
+}
+
+// WithAuditClock replaces the clock timestamping the records.
+func WithAuditClock(now func() time.Time) AuditOption {
+	return func(c *auditConfig) {
+		c.now = now
+	}
+}
+
+// userRepoAudit records every Create, Update and Delete in the store, in
+// the same transaction as the change, so a change is never missing from the
+// audit nor audited without being applied. The values before the change are
+// read locked inside the transaction. The updates and deletes of missing
+// users change nothing and are not recorded.
+type userRepoAudit struct {
+	interfaces.UsersRepo
+	store  AuditStore
+	config auditConfig
+}
+
+// NewUserRepoAudit audits the changes made through repo, the store must use
+// the same database.
+func NewUserRepoAudit(repo interfaces.UsersRepo, store AuditStore, opts ...AuditOption) interfaces.UsersRepo {
+	config := defaultAuditConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	return &userRepoAudit{UsersRepo: repo, store: store, config: config}
+}
+
+func (r userRepoAudit) record(ctx context.Context, operation string, id int64, before, after map[string]interface{}) AuditRecord {
+	return AuditRecord{
+		Actor:     ActorFromContext(ctx),
+		Operation: operation,
+		UserID:    id,
+		Before:    before,
+		After:     after,
+		CreatedAt: r.config.now(),
+	}
+}
+
+func (r userRepoAudit) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	return r.store.Transaction(ctx, opts, func(ctx context.Context, opts []utils.Options) error {
+		if err := r.UsersRepo.Create(ctx, user, opts...); err != nil {
+			return err
+		}
+
+		return r.store.Write(ctx, opts, r.record(ctx, MethodCreate, int64(user.ID), nil, userValues(user)))
+	})
+}
+
+func (r userRepoAudit) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	return r.store.Transaction(ctx, opts, func(ctx context.Context, opts []utils.Options) error {
+		current, err := r.UsersRepo.GetById(ctx, int64(user.ID), lockedRead(opts)...)
+		if err != nil {
+			return err
+		}
+
+		if err := r.UsersRepo.Update(ctx, user, vals, opts...); err != nil {
+			return err
+		}
+
+		// The gorm repositories return an empty user when it is missing.
+		if current == nil || current.ID == 0 {
+			return nil
+		}
+
+		values := userValues(current)
+		before, after := map[string]interface{}{}, map[string]interface{}{}
+		for field, v := range vals {
+			before[field] = values[field]
+			after[field] = v
+		}
+
+		return r.store.Write(ctx, opts, r.record(ctx, MethodUpdate, int64(user.ID), before, after))
+	})
+}
+
+func (r userRepoAudit) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	return r.store.Transaction(ctx, opts, func(ctx context.Context, opts []utils.Options) error {
+		users, _, err := r.UsersRepo.GetByIDs(ctx, ids, lockedRead(opts)...)
+		if err != nil {
+			return err
+		}
+
+		if err := r.UsersRepo.Delete(ctx, ids, opts...); err != nil {
+			return err
+		}
+
+		records := make([]AuditRecord, len(users))
+		for i, u := range users {
+			records[i] = r.record(ctx, MethodDelete, int64(u.ID), userValues(u), nil)
+		}
+
+		return r.store.Write(ctx, opts, records...)
+	})
+}
+
+// userValues returns the columns of the user with their values.
+func userValues(u *interfaces.User) map[string]interface{} {
+	return map[string]interface{}{
+		interfaces.FieldID:        u.ID,
+		interfaces.FieldName:      u.Name,
+		interfaces.FieldAge:       u.Age,
+		interfaces.FieldCreatedAt: u.CreatedAt,
+		interfaces.FieldUpdatedAt: u.UpdatedAt,
+	}
+}
@@ -0,0 +1,202 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+var auditNow = time.Date(2024, 4, 16, 10, 0, 0, 0, time.UTC)
+
+// failingAuditStore fails every write, the changes must be rolled back.
+type failingAuditStore struct {
+	repositories.AuditStore
+}
+
+func (s failingAuditStore) Write(ctx context.Context, opts []utils.Options, records ...repositories.AuditRecord) error {
+	return errors.New("audit unavailable")
+}
+
+// testUserRepoAudit checks the records written for the changes of r, a
+// repository loaded with the canonical fixtures. records returns the
+// records stored so far, in order.
+func testUserRepoAudit(t *testing.T, r interfaces.UsersRepo, store repositories.AuditStore, records func() []repositories.AuditRecord) {
+	ctx := repositories.WithActor(context.Background(), "admin")
+	audited := repositories.NewUserRepoAudit(r, store, repositories.WithAuditClock(func() time.Time { return auditNow }))
+
+	t.Run("create", func(t *testing.T) {
+		u := &interfaces.User{ID: 100, Name: "john", Age: 30}
+		if err := audited.Create(ctx, u); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+
+		got := records()
+		if len(got) != 1 {
+			t.Fatalf("expected 1 record, got %d", len(got))
+		}
+
+		assert.Equal(t, "admin", got[0].Actor, "they should be equal")
+		assert.Equal(t, repositories.MethodCreate, got[0].Operation, "they should be equal")
+		assert.Equal(t, int64(100), got[0].UserID, "they should be equal")
+		assert.Empty(t, got[0].Before, "should be empty")
+		assert.EqualValues(t, "john", got[0].After[interfaces.FieldName], "they should be equal")
+		assert.True(t, auditNow.Equal(got[0].CreatedAt), "they should be equal")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		if err := audited.Update(ctx, &interfaces.User{ID: 1}, map[string]interface{}{interfaces.FieldName: "renamed"}); err != nil {
+			t.Fatalf("updating user: %v", err)
+		}
+
+		got := records()
+		last := got[len(got)-1]
+
+		assert.Equal(t, 2, len(got), "they should be equal")
+		assert.Equal(t, repositories.MethodUpdate, last.Operation, "they should be equal")
+		assert.Equal(t, int64(1), last.UserID, "they should be equal")
+		assert.Equal(t, map[string]interface{}{interfaces.FieldName: "first"}, last.Before, "they should be equal")
+		assert.Equal(t, map[string]interface{}{interfaces.FieldName: "renamed"}, last.After, "they should be equal")
+	})
+
+	t.Run("update missing user", func(t *testing.T) {
+		if err := audited.Update(ctx, &interfaces.User{ID: 42}, map[string]interface{}{interfaces.FieldName: "nobody"}); err != nil {
+			t.Fatalf("updating user: %v", err)
+		}
+
+		assert.Equal(t, 2, len(records()), "they should be equal")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := audited.Delete(ctx, []int64{2, 3, 42}); err != nil {
+			t.Fatalf("deleting users: %v", err)
+		}
+
+		got := records()[2:]
+		if len(got) != 2 {
+			t.Fatalf("expected 2 records, got %d", len(got))
+		}
+
+		assert.ElementsMatch(t, []int64{2, 3}, []int64{got[0].UserID, got[1].UserID}, "they should be equal")
+		for _, rec := range got {
+			assert.Equal(t, repositories.MethodDelete, rec.Operation, "they should be equal")
+			assert.NotEmpty(t, rec.Before[interfaces.FieldName], "should not be empty")
+			assert.Empty(t, rec.After, "should be empty")
+		}
+	})
+
+	t.Run("rolled back with the audit", func(t *testing.T) {
+		failing := repositories.NewUserRepoAudit(r, failingAuditStore{store})
+
+		err := failing.Create(ctx, &interfaces.User{ID: 101, Name: "lost"})
+		assert.Error(t, err, "should fail")
+
+		user, err := r.GetById(context.Background(), 101)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Empty(t, user, "should be empty")
+		assert.Equal(t, 4, len(records()), "they should be equal")
+	})
+}
+
+func TestUserSQLiteRepoAudit(t *testing.T) {
+	ctx := context.Background()
+	db := NewTestSQLite(ctx, t)
+
+	if err := repositories.MigrateAudit(ctx, db); err != nil {
+		t.Fatalf("creating audit table: %v", err)
+	}
+
+	r := repositories.NewUserRepoSQLite(db)
+	store := repositories.NewAuditStoreMysql(db)
+
+	testUserRepoAudit(t, r, store, func() []repositories.AuditRecord {
+		var records []repositories.AuditRecord
+		if err := db.Order("id").Find(&records).Error; err != nil {
+			t.Fatalf("get records: %v", err)
+		}
+
+		return records
+	})
+
+	t.Run("joins the transaction of the caller", func(t *testing.T) {
+		audited := repositories.NewUserRepoAudit(r, store)
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := audited.Create(ctx, &interfaces.User{ID: 102, Name: "rolled back"}, utils.WithTx(tx)); err != nil {
+				return err
+			}
+
+			return errors.New("rollback")
+		})
+		assert.Error(t, err, "should fail")
+
+		var count int64
+		if err := db.Model(&repositories.AuditRecord{}).Where("user_id = ?", 102).Count(&count).Error; err != nil {
+			t.Fatalf("count records: %v", err)
+		}
+
+		assert.Equal(t, int64(0), count, "they should be equal")
+	})
+}
+
+func TestUserMysqlRepoAudit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	if err := repositories.MigrateAudit(ctx, db); err != nil {
+		t.Fatalf("creating audit table: %v", err)
+	}
+
+	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	testUserRepoAudit(t, r, repositories.NewAuditStoreMysql(db), func() []repositories.AuditRecord {
+		var records []repositories.AuditRecord
+		if err := db.Order("id").Find(&records).Error; err != nil {
+			t.Fatalf("get records: %v", err)
+		}
+
+		return records
+	})
+}
+
+func TestUserMongoRepoAudit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMongo(t)
+
+	r := repositories.NewUserRepoMongo(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	testUserRepoAudit(t, r, repositories.NewAuditStoreMongo(db), func() []repositories.AuditRecord {
+		cursor, err := db.Collection("audit").Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{"_id", 1}}))
+		if err != nil {
+			t.Fatalf("get records: %v", err)
+		}
+
+		var records []repositories.AuditRecord
+		if err := cursor.All(ctx, &records); err != nil {
+			t.Fatalf("get records: %v", err)
+		}
+
+		return records
+	})
+}
@@ -0,0 +1,267 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+)
+
+type batchConfig struct {
+	wait     time.Duration
+	maxBatch int
+	timeout  time.Duration
+}
+
+type BatchOption func(*batchConfig)
+
+func defaultBatchConfig() batchConfig {
+	return batchConfig{
+		wait:     2 * time.Millisecond,
+		maxBatch: utils.IDsChunk,
+		timeout:  5 * time.Second,
+	}
+}
+
+// WithBatchWait sets how long GetById calls are collected before being sent as one query.
+func WithBatchWait(wait time.Duration) BatchOption {
+	return func(c *batchConfig) {
+		c.wait = wait
+	}
+}
+
+// WithMaxBatch sends the batch as soon as it holds max ids.
+func WithMaxBatch(max int) BatchOption {
+	return func(c *batchConfig) {
+		c.maxBatch = max
+	}
+}
+
+// WithBatchTimeout sets how long the query of a batch may take once sent,
+// the batch is cancelled after its wait window and timeout.
+func WithBatchTimeout(timeout time.Duration) BatchOption {
+	return func(c *batchConfig) {
+		c.timeout = timeout
+	}
+}
+
+// userRepoBatch coalesces concurrent GetById calls into a single GetByIDs
+// query. Calls with utils.Options are not batched since they may change the
+// query, and every other method goes straight to the wrapped repository.
+type userRepoBatch struct {
+	interfaces.UsersRepo
+	batcher *userBatcher
+}
+
+func NewUserRepoBatch(repo interfaces.UsersRepo, opts ...BatchOption) interfaces.UsersRepo {
+	config := defaultBatchConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	return &userRepoBatch{
+		UsersRepo: repo,
+		batcher:   &userBatcher{repo: repo, config: config},
+	}
+}
+
+type loaderKey struct{}
+
+// WithUserLoader returns a context caching the users loaded by GetById until
+// it is discarded, usually at the end of the request.
+func WithUserLoader(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &userLoader{thunks: map[int64]*userThunk{}})
+}
+
+func (r userRepoBatch) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	if len(opts) > 0 {
+		return r.UsersRepo.GetById(ctx, id, opts...)
+	}
+
+	loader, _ := ctx.Value(loaderKey{}).(*userLoader)
+
+	var thunk *userThunk
+	if loader != nil {
+		thunk = loader.get(id, func() *userThunk { return r.batcher.load(ctx, id) })
+	} else {
+		thunk = r.batcher.load(ctx, id)
+	}
+
+	select {
+	case <-thunk.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if thunk.err != nil {
+		if loader != nil {
+			loader.forget(id, thunk)
+		}
+
+		return nil, thunk.err
+	}
+
+	return copyUser(thunk.user), nil
+}
+
+func (r userRepoBatch) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	if err := r.UsersRepo.Create(ctx, user, opts...); err != nil {
+		return err
+	}
+
+	if loader, ok := ctx.Value(loaderKey{}).(*userLoader); ok {
+		loader.forget(int64(user.ID), nil)
+	}
+
+	return nil
+}
+
+func (r userRepoBatch) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	if loader, ok := ctx.Value(loaderKey{}).(*userLoader); ok {
+		loader.forget(int64(user.ID), nil)
+	}
+
+	return r.UsersRepo.Update(ctx, user, vals, opts...)
+}
+
+func (r userRepoBatch) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	if loader, ok := ctx.Value(loaderKey{}).(*userLoader); ok {
+		for _, id := range ids {
+			loader.forget(id, nil)
+		}
+	}
+
+	return r.UsersRepo.Delete(ctx, ids, opts...)
+}
+
+// userThunk is the pending result of a single id, done is closed once it is loaded.
+type userThunk struct {
+	done chan struct{}
+	user *interfaces.User
+	err  error
+}
+
+// userLoader is the per request cache of the loaded ids.
+type userLoader struct {
+	mu     sync.Mutex
+	thunks map[int64]*userThunk
+}
+
+func (l *userLoader) get(id int64, load func() *userThunk) *userThunk {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if thunk, ok := l.thunks[id]; ok {
+		return thunk
+	}
+
+	thunk := load()
+	l.thunks[id] = thunk
+
+	return thunk
+}
+
+// forget drops the cached id, only if it still holds thunk when thunk is not nil.
+func (l *userLoader) forget(id int64, thunk *userThunk) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if thunk == nil || l.thunks[id] == thunk {
+		delete(l.thunks, id)
+	}
+}
+
+// userBatcher collects the ids requested during the wait window.
+type userBatcher struct {
+	repo   interfaces.UsersRepo
+	config batchConfig
+
+	mu         sync.Mutex
+	generation int
+	ctx        context.Context
+	cancel     context.CancelFunc
+	pending    map[int64]*userThunk
+	ids        []int64
+}
+
+func (b *userBatcher) load(ctx context.Context, id int64) *userThunk {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if thunk, ok := b.pending[id]; ok {
+		return thunk
+	}
+
+	if b.pending == nil {
+		b.pending = map[int64]*userThunk{}
+		// the batch outlives the call that opened it, so it must not be
+		// cancelled with it, but with its own deadline. Every caller stops
+		// waiting on its own context.
+		b.ctx, b.cancel = context.WithTimeout(context.WithoutCancel(ctx), b.config.wait+b.config.timeout)
+		b.generation++
+
+		generation := b.generation
+		time.AfterFunc(b.config.wait, func() { b.flush(generation) })
+	}
+
+	thunk := &userThunk{done: make(chan struct{})}
+	b.pending[id] = thunk
+	b.ids = append(b.ids, id)
+
+	if len(b.ids) >= b.config.maxBatch {
+		go b.dispatch(b.take())
+	}
+
+	return thunk
+}
+
+// flush dispatches the batch unless it was already sent for being full.
+func (b *userBatcher) flush(generation int) {
+	b.mu.Lock()
+	if b.generation != generation {
+		b.mu.Unlock()
+		return
+	}
+
+	pending := b.take()
+	b.mu.Unlock()
+
+	b.dispatch(pending)
+}
+
+type userBatch struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	ids     []int64
+	pending map[int64]*userThunk
+}
+
+// take detaches the pending batch, it must be called holding the lock.
+func (b *userBatcher) take() userBatch {
+	batch := userBatch{ctx: b.ctx, cancel: b.cancel, ids: b.ids, pending: b.pending}
+	b.ctx, b.cancel, b.ids, b.pending = nil, nil, nil, nil
+	b.generation++
+
+	return batch
+}
+
+func (b *userBatcher) dispatch(batch userBatch) {
+	if len(batch.ids) == 0 {
+		return
+	}
+	defer batch.cancel()
+
+	users, _, err := b.repo.GetByIDs(batch.ctx, batch.ids)
+
+	byID := make(map[int64]*interfaces.User, len(users))
+	for _, u := range users {
+		byID[int64(u.ID)] = u
+	}
+
+	for id, thunk := range batch.pending {
+		thunk.user, thunk.err = byID[id], err
+		close(thunk.done)
+	}
+}
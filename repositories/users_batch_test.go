@@ -0,0 +1,183 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepoBatchGetById(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("concurrent calls share a query", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "first"}, interfaces.User{ID: 2, Name: "second"})
+		r := repositories.NewUserRepoBatch(fake, repositories.WithBatchWait(20*time.Millisecond))
+
+		ids := []int64{1, 2, 1, 3}
+		users := make([]*interfaces.User, len(ids))
+
+		var wg sync.WaitGroup
+		for i, id := range ids {
+			wg.Add(1)
+			go func(i int, id int64) {
+				defer wg.Done()
+
+				user, err := r.GetById(ctx, id)
+				if err != nil {
+					t.Errorf("get user: %v", err)
+				}
+				users[i] = user
+			}(i, id)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetByIDs)), "they should be equal")
+		assert.Equal(t, 0, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+		assert.Equal(t, "first", users[0].Name, "they should be equal")
+		assert.Equal(t, "second", users[1].Name, "they should be equal")
+		assert.Equal(t, "first", users[2].Name, "they should be equal")
+		assert.Nil(t, users[3])
+	})
+
+	t.Run("full batch is sent early", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1}, interfaces.User{ID: 2})
+		r := repositories.NewUserRepoBatch(fake, repositories.WithBatchWait(time.Hour), repositories.WithMaxBatch(2))
+
+		var wg sync.WaitGroup
+		for _, id := range []int64{1, 2} {
+			wg.Add(1)
+			go func(id int64) {
+				defer wg.Done()
+
+				if _, err := r.GetById(ctx, id); err != nil {
+					t.Errorf("get user: %v", err)
+				}
+			}(id)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetByIDs)), "they should be equal")
+	})
+
+	t.Run("request cache", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "first"})
+		r := repositories.NewUserRepoBatch(fake)
+		ctx := repositories.WithUserLoader(ctx)
+
+		for i := 0; i < 3; i++ {
+			user, err := r.GetById(ctx, 1)
+			if err != nil {
+				t.Fatalf("get user: %v", err)
+			}
+
+			assert.Equal(t, "first", user.Name, "they should be equal")
+		}
+
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetByIDs)), "they should be equal")
+
+		if err := r.Update(ctx, &interfaces.User{ID: 1}, map[string]interface{}{"name": "new name"}); err != nil {
+			t.Fatalf("update user: %v", err)
+		}
+
+		user, err := r.GetById(ctx, 1)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Equal(t, "new name", user.Name, "they should be equal")
+		assert.Equal(t, 2, len(fake.CallsTo(repositories.MethodGetByIDs)), "they should be equal")
+	})
+
+	t.Run("missing user created in the request", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo()
+		r := repositories.NewUserRepoBatch(fake)
+		ctx := repositories.WithUserLoader(ctx)
+
+		user, err := r.GetById(ctx, 1)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Nil(t, user)
+
+		if err := r.Create(ctx, &interfaces.User{ID: 1, Name: "first"}); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+
+		user, err = r.GetById(ctx, 1)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Equal(t, "first", user.Name, "they should be equal")
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1})
+		refused := errors.New("connection refused")
+		fake.Fail(refused)
+		r := repositories.NewUserRepoBatch(fake)
+		ctx := repositories.WithUserLoader(ctx)
+
+		_, err := r.GetById(ctx, 1)
+		assert.Equal(t, refused, err, "they should be equal")
+
+		fake.Fail(nil)
+
+		user, err := r.GetById(ctx, 1)
+
+		assert.Nil(t, err)
+		assert.Equal(t, uint(1), user.ID, "they should be equal")
+	})
+
+	t.Run("batch timeout", func(t *testing.T) {
+		r := repositories.NewUserRepoBatch(hangingUsersRepo{mocks.NewRecordingUsersRepo()}, repositories.WithBatchTimeout(10*time.Millisecond))
+
+		_, err := r.GetById(ctx, 1)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "should time out")
+	})
+
+	t.Run("callers stop on their own context", func(t *testing.T) {
+		r := repositories.NewUserRepoBatch(hangingUsersRepo{mocks.NewRecordingUsersRepo()}, repositories.WithBatchTimeout(time.Hour))
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := r.GetById(ctx, 1)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "should time out")
+	})
+
+	t.Run("options skip the batch", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1})
+		r := repositories.NewUserRepoBatch(fake)
+
+		if _, err := r.GetById(ctx, 1, utils.FromMasterReplica); err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+		assert.Equal(t, 0, len(fake.CallsTo(repositories.MethodGetByIDs)), "they should be equal")
+	})
+}
+
+// hangingUsersRepo blocks GetByIDs until its context is done.
+type hangingUsersRepo struct {
+	interfaces.UsersRepo
+}
+
+func (h hangingUsersRepo) GetByIDs(ctx context.Context, ids []int64, opts ...utils.Options) ([]*interfaces.User, []int64, error) {
+	<-ctx.Done()
+
+	return nil, nil, ctx.Err()
+}
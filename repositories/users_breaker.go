@@ -0,0 +1,347 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrCircuitOpen is returned without calling the wrapped repository while
+// the circuit of the method is open.
+var ErrCircuitOpen = errors.New("users repository circuit is open")
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitEvent reports the transition of the circuit of Method.
+type CircuitEvent struct {
+	Method string
+	From   CircuitState
+	To     CircuitState
+}
+
+type breakerConfig struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	failure          func(error) bool
+	onStateChange    func(context.Context, CircuitEvent)
+	meterProvider    metric.MeterProvider
+}
+
+type BreakerOption func(*breakerConfig)
+
+func defaultBreakerConfig() breakerConfig {
+	return breakerConfig{
+		failureThreshold: 5,
+		openTimeout:      30 * time.Second,
+		halfOpenProbes:   1,
+		failure:          isBackendFailure,
+		meterProvider:    otel.GetMeterProvider(),
+	}
+}
+
+// isBackendFailure ignores the errors caused by the caller, invalid filters
+// or a cancelled context, which say nothing about the health of the backend.
+func isBackendFailure(err error) bool {
+	var verr *interfaces.ValidationError
+
+	return err != nil && !errors.As(err, &verr) && !errors.Is(err, context.Canceled)
+}
+
+// WithFailureThreshold opens the circuit of a method after n consecutive failures.
+func WithFailureThreshold(n int) BreakerOption {
+	return func(c *breakerConfig) {
+		c.failureThreshold = n
+	}
+}
+
+// WithOpenTimeout sets how long a circuit stays open before letting probes through.
+func WithOpenTimeout(timeout time.Duration) BreakerOption {
+	return func(c *breakerConfig) {
+		c.openTimeout = timeout
+	}
+}
+
+// WithHalfOpenProbes sets how many calls are let through a half-open
+// circuit, all of them must succeed for it to close.
+func WithHalfOpenProbes(n int) BreakerOption {
+	return func(c *breakerConfig) {
+		c.halfOpenProbes = n
+	}
+}
+
+// WithFailureErrors replaces the classification of the errors counted as
+// failures of the backend. The other errors are not counted as successes
+// either, only the calls without an error are.
+func WithFailureErrors(failure func(error) bool) BreakerOption {
+	return func(c *breakerConfig) {
+		c.failure = failure
+	}
+}
+
+// WithStateChange calls fn on every transition of a circuit.
+func WithStateChange(fn func(context.Context, CircuitEvent)) BreakerOption {
+	return func(c *breakerConfig) {
+		c.onStateChange = fn
+	}
+}
+
+// WithBreakerMeterProvider sets the provider of the transitions and
+// rejections metrics, otel.GetMeterProvider() by default.
+func WithBreakerMeterProvider(provider metric.MeterProvider) BreakerOption {
+	return func(c *breakerConfig) {
+		c.meterProvider = provider
+	}
+}
+
+// circuit is the state of the circuit of one method. The generation changes
+// on every transition so the calls started before it are not counted.
+type circuit struct {
+	mu         sync.Mutex
+	state      CircuitState
+	generation uint64
+	failures   int
+	probes     int
+	successes  int
+	openedAt   time.Time
+}
+
+// userRepoBreaker fails fast with ErrCircuitOpen the calls to a method whose
+// last calls failed, instead of waiting for the degraded backend. Each method
+// has its own circuit, so a failing Stats does not reject GetById.
+type userRepoBreaker struct {
+	interfaces.UsersRepo
+	config      breakerConfig
+	circuits    map[string]*circuit
+	transitions metric.Int64Counter
+	rejected    metric.Int64Counter
+}
+
+func NewUserRepoBreaker(repo interfaces.UsersRepo, opts ...BreakerOption) interfaces.UsersRepo {
+	config := defaultBreakerConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	meter := config.meterProvider.Meter(instrumentationName)
+
+	transitions, err := meter.Int64Counter(
+		"users_repo.circuit.transitions",
+		metric.WithDescription("Number of state changes of the users repository circuits."),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	rejected, err := meter.Int64Counter(
+		"users_repo.circuit.rejected",
+		metric.WithDescription("Number of users repository calls rejected by an open circuit."),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	circuits := map[string]*circuit{}
+	for _, method := range []string{MethodGetById, MethodGetAll, MethodGetByIDs, MethodCreate, MethodUpdate, MethodDelete, MethodStats} {
+		circuits[method] = &circuit{}
+	}
+
+	return &userRepoBreaker{
+		UsersRepo:   repo,
+		config:      config,
+		circuits:    circuits,
+		transitions: transitions,
+		rejected:    rejected,
+	}
+}
+
+// allow reports whether a call to method may reach the backend, and the
+// generation of the circuit to report its result to.
+func (r userRepoBreaker) allow(ctx context.Context, method string) (uint64, bool) {
+	c := r.circuits[method]
+
+	c.mu.Lock()
+	var events []CircuitEvent
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= r.config.openTimeout {
+		events = append(events, r.transition(c, method, CircuitHalfOpen))
+	}
+
+	ok := c.state == CircuitClosed || (c.state == CircuitHalfOpen && c.probes < r.config.halfOpenProbes)
+	if ok && c.state == CircuitHalfOpen {
+		c.probes++
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	r.notify(ctx, events...)
+
+	if !ok {
+		r.rejected.Add(ctx, 1, metric.WithAttributes(attribute.String("users.method", method)))
+	}
+
+	return generation, ok
+}
+
+// done records the result of a call allowed in generation.
+func (r userRepoBreaker) done(ctx context.Context, method string, generation uint64, err error) {
+	c := r.circuits[method]
+	failed := r.config.failure(err)
+
+	c.mu.Lock()
+	var events []CircuitEvent
+	switch {
+	case generation != c.generation:
+	case failed && c.state == CircuitHalfOpen:
+		events = append(events, r.transition(c, method, CircuitOpen))
+	case failed:
+		c.failures++
+		if c.failures >= r.config.failureThreshold {
+			events = append(events, r.transition(c, method, CircuitOpen))
+		}
+	case err != nil:
+		// neither a failure nor a success, a cancelled or invalid call only
+		// frees its probe.
+		if c.state == CircuitHalfOpen {
+			c.probes--
+		}
+	case c.state == CircuitHalfOpen:
+		c.successes++
+		if c.successes >= r.config.halfOpenProbes {
+			events = append(events, r.transition(c, method, CircuitClosed))
+		}
+	default:
+		c.failures = 0
+	}
+	c.mu.Unlock()
+
+	r.notify(ctx, events...)
+}
+
+// transition must be called with the lock of c held.
+func (r userRepoBreaker) transition(c *circuit, method string, to CircuitState) CircuitEvent {
+	event := CircuitEvent{Method: method, From: c.state, To: to}
+
+	c.state = to
+	c.generation++
+	c.failures, c.probes, c.successes = 0, 0, 0
+	if to == CircuitOpen {
+		c.openedAt = time.Now()
+	}
+
+	return event
+}
+
+func (r userRepoBreaker) notify(ctx context.Context, events ...CircuitEvent) {
+	for _, e := range events {
+		r.transitions.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("users.method", e.Method),
+			attribute.String("from", e.From.String()),
+			attribute.String("to", e.To.String()),
+		))
+
+		if r.config.onStateChange != nil {
+			r.config.onStateChange(ctx, e)
+		}
+	}
+}
+
+func (r userRepoBreaker) do(ctx context.Context, method string, op func() error) error {
+	generation, ok := r.allow(ctx, method)
+	if !ok {
+		return ErrCircuitOpen
+	}
+
+	err := op()
+	r.done(ctx, method, generation, err)
+
+	return err
+}
+
+func (r userRepoBreaker) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	var user *interfaces.User
+	err := r.do(ctx, MethodGetById, func() (err error) {
+		user, err = r.UsersRepo.GetById(ctx, id, opts...)
+		return err
+	})
+
+	return user, err
+}
+
+func (r userRepoBreaker) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	var (
+		users []*interfaces.User
+		total int64
+	)
+	err := r.do(ctx, MethodGetAll, func() (err error) {
+		users, total, err = r.UsersRepo.GetAll(ctx, filters, opts...)
+		return err
+	})
+
+	return users, total, err
+}
+
+func (r userRepoBreaker) GetByIDs(ctx context.Context, ids []int64, opts ...utils.Options) ([]*interfaces.User, []int64, error) {
+	var (
+		users   []*interfaces.User
+		missing []int64
+	)
+	err := r.do(ctx, MethodGetByIDs, func() (err error) {
+		users, missing, err = r.UsersRepo.GetByIDs(ctx, ids, opts...)
+		return err
+	})
+
+	return users, missing, err
+}
+
+func (r userRepoBreaker) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	return r.do(ctx, MethodCreate, func() error {
+		return r.UsersRepo.Create(ctx, user, opts...)
+	})
+}
+
+func (r userRepoBreaker) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	return r.do(ctx, MethodUpdate, func() error {
+		return r.UsersRepo.Update(ctx, user, vals, opts...)
+	})
+}
+
+func (r userRepoBreaker) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	return r.do(ctx, MethodDelete, func() error {
+		return r.UsersRepo.Delete(ctx, ids, opts...)
+	})
+}
+
+func (r userRepoBreaker) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	var stats *interfaces.UserStats
+	err := r.do(ctx, MethodStats, func() (err error) {
+		stats, err = r.UsersRepo.Stats(ctx, filters, query, opts...)
+		return err
+	})
+
+	return stats, err
+}
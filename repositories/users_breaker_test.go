@@ -0,0 +1,179 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/repositories"
+
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestUserRepoBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu     sync.Mutex
+		events []repositories.CircuitEvent
+	)
+	reader := sdkmetric.NewManualReader()
+	refused := errors.New("connection refused")
+
+	fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1})
+	r := repositories.NewUserRepoBreaker(fake,
+		repositories.WithFailureThreshold(2),
+		repositories.WithOpenTimeout(20*time.Millisecond),
+		repositories.WithBreakerMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		repositories.WithStateChange(func(ctx context.Context, e repositories.CircuitEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		}),
+	)
+
+	t.Run("failures open the circuit of the method", func(t *testing.T) {
+		fake.Fail(refused)
+		for i := 0; i < 2; i++ {
+			_, err := r.GetById(ctx, 1)
+			assert.Equal(t, refused, err, "they should be equal")
+		}
+
+		_, err := r.GetById(ctx, 1)
+		assert.Equal(t, repositories.ErrCircuitOpen, err, "they should be equal")
+		assert.Equal(t, 2, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+
+		fake.Fail(nil)
+		_, _, err = r.GetAll(ctx, interfaces.Filters{})
+		assert.Nil(t, err, "should be nil")
+	})
+
+	t.Run("validation errors are not failures", func(t *testing.T) {
+		invalid := &interfaces.ValidationError{}
+		fake.Fail(invalid)
+		for i := 0; i < 3; i++ {
+			_, _, err := r.GetAll(ctx, interfaces.Filters{})
+			assert.Equal(t, invalid, err, "they should be equal")
+		}
+		fake.Fail(nil)
+
+		_, _, err := r.GetAll(ctx, interfaces.Filters{})
+		assert.Nil(t, err, "should be nil")
+	})
+
+	t.Run("a successful probe closes the circuit", func(t *testing.T) {
+		time.Sleep(30 * time.Millisecond)
+
+		user, err := r.GetById(ctx, 1)
+		assert.Nil(t, err, "should be nil")
+		assert.Equal(t, uint(1), user.ID, "they should be equal")
+
+		_, err = r.GetById(ctx, 1)
+		assert.Nil(t, err, "should be nil")
+	})
+
+	t.Run("a failed probe opens the circuit again", func(t *testing.T) {
+		fake.Fail(refused)
+		for i := 0; i < 2; i++ {
+			_ = r.Delete(ctx, []int64{1})
+		}
+		time.Sleep(30 * time.Millisecond)
+
+		err := r.Delete(ctx, []int64{1})
+		assert.Equal(t, refused, err, "they should be equal")
+
+		err = r.Delete(ctx, []int64{1})
+		assert.Equal(t, repositories.ErrCircuitOpen, err, "they should be equal")
+		fake.Fail(nil)
+	})
+
+	mu.Lock()
+	assert.Equal(t, []repositories.CircuitEvent{
+		{Method: repositories.MethodGetById, From: repositories.CircuitClosed, To: repositories.CircuitOpen},
+		{Method: repositories.MethodGetById, From: repositories.CircuitOpen, To: repositories.CircuitHalfOpen},
+		{Method: repositories.MethodGetById, From: repositories.CircuitHalfOpen, To: repositories.CircuitClosed},
+		{Method: repositories.MethodDelete, From: repositories.CircuitClosed, To: repositories.CircuitOpen},
+		{Method: repositories.MethodDelete, From: repositories.CircuitOpen, To: repositories.CircuitHalfOpen},
+		{Method: repositories.MethodDelete, From: repositories.CircuitHalfOpen, To: repositories.CircuitOpen},
+	}, events, "they should be equal")
+	mu.Unlock()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("collecting metrics: %v", err)
+	}
+
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	transitions := metrics["users_repo.circuit.transitions"].Data.(metricdata.Sum[int64])
+	assert.Equal(t, 6, len(transitions.DataPoints), "they should be equal")
+
+	rejected := metrics["users_repo.circuit.rejected"].Data.(metricdata.Sum[int64])
+	assert.Equal(t, 2, len(rejected.DataPoints), "they should be equal")
+}
+
+func TestUserRepoBreakerCallerErrors(t *testing.T) {
+	ctx := context.Background()
+	backend := errors.New("connection refused")
+
+	newBreaker := func(fake interfaces.UsersRepo, events *[]repositories.CircuitEvent) interfaces.UsersRepo {
+		return repositories.NewUserRepoBreaker(fake,
+			repositories.WithFailureThreshold(2),
+			repositories.WithOpenTimeout(20*time.Millisecond),
+			repositories.WithStateChange(func(ctx context.Context, e repositories.CircuitEvent) {
+				*events = append(*events, e)
+			}),
+		)
+	}
+
+	t.Run("do not reset the failures", func(t *testing.T) {
+		var events []repositories.CircuitEvent
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1})
+		r := newBreaker(fake, &events)
+
+		for _, err := range []error{backend, context.Canceled, backend} {
+			fake.Fail(err)
+			_, _ = r.GetById(ctx, 1)
+		}
+
+		_, err := r.GetById(ctx, 1)
+		assert.Equal(t, repositories.ErrCircuitOpen, err, "they should be equal")
+	})
+
+	t.Run("do not close the circuit", func(t *testing.T) {
+		var events []repositories.CircuitEvent
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1})
+		r := newBreaker(fake, &events)
+
+		fake.Fail(backend)
+		for i := 0; i < 2; i++ {
+			_, _ = r.GetById(ctx, 1)
+		}
+		time.Sleep(30 * time.Millisecond)
+
+		invalid := &interfaces.ValidationError{}
+		fake.Fail(invalid)
+		_, err := r.GetById(ctx, 1)
+		assert.Equal(t, invalid, err, "they should be equal")
+
+		// the probe was freed, the next call probes the backend again.
+		fake.Fail(backend)
+		_, err = r.GetById(ctx, 1)
+		assert.Equal(t, backend, err, "they should be equal")
+
+		assert.Equal(t, []repositories.CircuitEvent{
+			{Method: repositories.MethodGetById, From: repositories.CircuitClosed, To: repositories.CircuitOpen},
+			{Method: repositories.MethodGetById, From: repositories.CircuitOpen, To: repositories.CircuitHalfOpen},
+			{Method: repositories.MethodGetById, From: repositories.CircuitHalfOpen, To: repositories.CircuitOpen},
+		}, events, "they should be equal")
+	})
+}
@@ -0,0 +1,308 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	userKeyPrefix   = "users:"
+	listVersionKey  = "users:list:version"
+	listKeyTemplate = "users:list:%s:%s"
+)
+
+type cacheConfig struct {
+	ttl     time.Duration
+	listTTL time.Duration
+}
+
+type CacheOption func(*cacheConfig)
+
+func defaultCacheConfig() cacheConfig {
+	return cacheConfig{
+		ttl:     5 * time.Minute,
+		listTTL: 0,
+	}
+}
+
+// WithCacheTTL sets how long the users loaded by id are cached.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithListTTL enables caching the GetAll pages for ttl.
+func WithListTTL(ttl time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.listTTL = ttl
+	}
+}
+
+// userRepoCache is a read through cache of the users by id, and optionally
+// of the GetAll pages. Reads with utils.Options always reach the wrapped
+// repository and writes drop the affected ids and every cached page. Cache
+// failures are not reported, the wrapped repository is used instead.
+type userRepoCache struct {
+	interfaces.UsersRepo
+	cache  interfaces.Cache
+	config cacheConfig
+	group  *singleflight.Group
+	// generation is bumped by every invalidation, the users loaded before
+	// one are not cached since they may predate the write.
+	generation *atomic.Uint64
+}
+
+func NewUserRepoCache(repo interfaces.UsersRepo, cache interfaces.Cache, opts ...CacheOption) interfaces.UsersRepo {
+	config := defaultCacheConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	return &userRepoCache{
+		UsersRepo:  repo,
+		cache:      cache,
+		config:     config,
+		group:      &singleflight.Group{},
+		generation: &atomic.Uint64{},
+	}
+}
+
+func UserCacheKey(id int64) string {
+	return userKeyPrefix + strconv.FormatInt(id, 10)
+}
+
+func (r userRepoCache) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	if len(opts) > 0 {
+		return r.UsersRepo.GetById(ctx, id, opts...)
+	}
+
+	key := UserCacheKey(id)
+
+	var user *interfaces.User
+	if r.get(ctx, key, &user) {
+		return user, nil
+	}
+
+	v, err, _ := r.group.Do(key, func() (interface{}, error) {
+		generation := r.generation.Load()
+
+		user, err := r.UsersRepo.GetById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		r.setLoaded(ctx, generation, key, user)
+
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return copyUser(v.(*interfaces.User)), nil
+}
+
+func (r userRepoCache) GetByIDs(ctx context.Context, ids []int64, opts ...utils.Options) ([]*interfaces.User, []int64, error) {
+	if len(opts) > 0 {
+		return r.UsersRepo.GetByIDs(ctx, ids, opts...)
+	}
+
+	users := make([]*interfaces.User, 0, len(ids))
+	uncached := []int64{}
+	for _, id := range ids {
+		var user *interfaces.User
+		if !r.get(ctx, UserCacheKey(id), &user) {
+			uncached = append(uncached, id)
+		} else if user != nil {
+			users = append(users, user)
+		}
+	}
+
+	if len(uncached) > 0 {
+		generation := r.generation.Load()
+
+		loaded, missing, err := r.UsersRepo.GetByIDs(ctx, uncached)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, u := range loaded {
+			r.setLoaded(ctx, generation, UserCacheKey(int64(u.ID)), u)
+		}
+
+		for _, id := range missing {
+			r.setLoaded(ctx, generation, UserCacheKey(id), nil)
+		}
+
+		users = append(users, loaded...)
+	}
+
+	users, missing := sortByIDs(ids, users)
+
+	return users, missing, nil
+}
+
+type cachedPage struct {
+	Users []*interfaces.User
+	Total int64
+}
+
+func (r userRepoCache) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	if len(opts) > 0 || r.config.listTTL <= 0 {
+		return r.UsersRepo.GetAll(ctx, filters, opts...)
+	}
+
+	key, ok := r.listKey(ctx, filters)
+	if !ok {
+		return r.UsersRepo.GetAll(ctx, filters)
+	}
+
+	var page cachedPage
+	if r.get(ctx, key, &page) {
+		return page.Users, page.Total, nil
+	}
+
+	v, err, _ := r.group.Do(key, func() (interface{}, error) {
+		users, total, err := r.UsersRepo.GetAll(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+
+		page := cachedPage{Users: users, Total: total}
+		r.set(ctx, key, page, r.config.listTTL)
+
+		return page, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page = v.(cachedPage)
+	users := make([]*interfaces.User, len(page.Users))
+	for i, u := range page.Users {
+		users[i] = copyUser(u)
+	}
+
+	return users, page.Total, nil
+}
+
+// listKey hashes the normalised filters, prefixed with the current version
+// of the pages so bumping it on writes discards all of them at once.
+func (r userRepoCache) listKey(ctx context.Context, filters interfaces.Filters) (string, bool) {
+	filters, err := filters.Normalize()
+	if err != nil {
+		return "", false
+	}
+
+	version, ok, err := r.cache.Get(ctx, listVersionKey)
+	if err != nil {
+		return "", false
+	}
+
+	if !ok {
+		// a missing version must not reuse the pages of an evicted one.
+		version = []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+		if err := r.cache.Set(ctx, listVersionKey, version, 0); err != nil {
+			return "", false
+		}
+	}
+
+	data, err := json.Marshal(filters)
+	if err != nil {
+		return "", false
+	}
+
+	hash := sha256.Sum256(data)
+
+	return fmt.Sprintf(listKeyTemplate, version, hex.EncodeToString(hash[:])), true
+}
+
+func (r userRepoCache) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	err := r.UsersRepo.Create(ctx, user, opts...)
+	r.invalidate(ctx, int64(user.ID))
+
+	return err
+}
+
+func (r userRepoCache) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	err := r.UsersRepo.Update(ctx, user, vals, opts...)
+	r.invalidate(ctx, int64(user.ID))
+
+	return err
+}
+
+func (r userRepoCache) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	err := r.UsersRepo.Delete(ctx, ids, opts...)
+	r.invalidate(ctx, ids...)
+
+	return err
+}
+
+// invalidate drops the cached ids and the cached GetAll pages. It runs even
+// when the write failed, since it may have been partially applied.
+func (r userRepoCache) invalidate(ctx context.Context, ids ...int64) {
+	r.generation.Add(1)
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, UserCacheKey(id))
+	}
+
+	_ = r.cache.Delete(ctx, keys...)
+
+	if r.config.listTTL > 0 {
+		version := strconv.FormatInt(time.Now().UnixNano(), 10)
+		_ = r.cache.Set(ctx, listVersionKey, []byte(version), 0)
+	}
+}
+
+func (r userRepoCache) get(ctx context.Context, key string, v interface{}) bool {
+	data, ok, err := r.cache.Get(ctx, key)
+	if err != nil || !ok {
+		return false
+	}
+
+	return json.Unmarshal(data, v) == nil
+}
+
+// setLoaded caches a user loaded by id unless an invalidation ran since the
+// load started at generation. The generation is local to the process, the
+// writes of the other processes are only bounded by the ttl.
+func (r userRepoCache) setLoaded(ctx context.Context, generation uint64, key string, user *interfaces.User) {
+	if r.generation.Load() != generation {
+		return
+	}
+
+	r.set(ctx, key, user, r.config.ttl)
+}
+
+func (r userRepoCache) set(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	_ = r.cache.Set(ctx, key, data, ttl)
+}
+
+func copyUser(user *interfaces.User) *interfaces.User {
+	if user == nil {
+		return nil
+	}
+
+	u := *user
+
+	return &u
+}
@@ -0,0 +1,251 @@
+package repositories_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"repos/cache"
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepoCacheGetById(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("read through", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "first"})
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10))
+
+		for i := 0; i < 3; i++ {
+			user, err := r.GetById(ctx, 1)
+			if err != nil {
+				t.Fatalf("get user: %v", err)
+			}
+
+			assert.Equal(t, "first", user.Name, "they should be equal")
+		}
+
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+	})
+
+	t.Run("missing users are cached", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo()
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10))
+
+		for i := 0; i < 2; i++ {
+			user, err := r.GetById(ctx, 1)
+
+			assert.Nil(t, err)
+			assert.Nil(t, user)
+		}
+
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1})
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10), repositories.WithCacheTTL(time.Millisecond))
+
+		_, _ = r.GetById(ctx, 1)
+		time.Sleep(5 * time.Millisecond)
+		_, _ = r.GetById(ctx, 1)
+
+		assert.Equal(t, 2, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+	})
+
+	t.Run("concurrent misses share a query", func(t *testing.T) {
+		fake := &slowUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(interfaces.User{ID: 1}), delay: 20 * time.Millisecond}
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				if _, err := r.GetById(ctx, 1); err != nil {
+					t.Errorf("get user: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+	})
+
+	t.Run("write during a load", func(t *testing.T) {
+		fake := &pausedUsersRepo{
+			RecordingUsersRepo: mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "first"}),
+			loaded:             make(chan struct{}),
+			resume:             make(chan struct{}),
+		}
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			user, err := r.GetById(ctx, 1)
+			if err != nil {
+				t.Errorf("get user: %v", err)
+			}
+
+			assert.Equal(t, "first", user.Name, "they should be equal")
+		}()
+
+		<-fake.loaded
+
+		if err := r.Update(ctx, &interfaces.User{ID: 1}, map[string]interface{}{"name": "new name"}); err != nil {
+			t.Fatalf("update user: %v", err)
+		}
+
+		close(fake.resume)
+		<-done
+
+		user, err := r.GetById(ctx, 1)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Equal(t, "new name", user.Name, "they should be equal")
+	})
+
+	t.Run("writes invalidate", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "first"})
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10))
+
+		_, _ = r.GetById(ctx, 1)
+
+		if err := r.Update(ctx, &interfaces.User{ID: 1}, map[string]interface{}{"name": "new name"}); err != nil {
+			t.Fatalf("update user: %v", err)
+		}
+
+		user, _ := r.GetById(ctx, 1)
+		assert.Equal(t, "new name", user.Name, "they should be equal")
+
+		if err := r.Delete(ctx, []int64{1}); err != nil {
+			t.Fatalf("delete user: %v", err)
+		}
+
+		user, _ = r.GetById(ctx, 1)
+		assert.Nil(t, user)
+
+		if err := r.Create(ctx, &interfaces.User{ID: 1, Name: "again"}); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+
+		user, _ = r.GetById(ctx, 1)
+		assert.Equal(t, "again", user.Name, "they should be equal")
+		assert.Equal(t, 4, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+	})
+}
+
+func TestUserRepoCacheGetByIDs(t *testing.T) {
+	ctx := context.Background()
+
+	fake := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1}, interfaces.User{ID: 2})
+	r := repositories.NewUserRepoCache(fake, cache.NewLRU(10))
+
+	_, _ = r.GetById(ctx, 1)
+
+	users, missing, err := r.GetByIDs(ctx, []int64{2, 3, 1})
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	assert.Equal(t, uint(2), users[0].ID, "they should be equal")
+	assert.Equal(t, uint(1), users[1].ID, "they should be equal")
+	assert.Equal(t, []int64{3}, missing, "they should be equal")
+
+	_, _, _ = r.GetByIDs(ctx, []int64{2, 3, 1})
+
+	assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetByIDs)), "they should be equal")
+}
+
+func TestUserRepoCacheGetAll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(fixtures.NewUser(fixtures.WithID(1)))
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10))
+
+		_, _, _ = r.GetAll(ctx, interfaces.Filters{})
+		_, _, _ = r.GetAll(ctx, interfaces.Filters{})
+
+		assert.Equal(t, 2, len(fake.CallsTo(repositories.MethodGetAll)), "they should be equal")
+	})
+
+	t.Run("pages keyed by normalised filters", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(fixtures.NewUser(fixtures.WithID(1)))
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10), repositories.WithListTTL(time.Minute))
+
+		_, _, _ = r.GetAll(ctx, interfaces.Filters{})
+		users, total, err := r.GetAll(ctx, interfaces.Filters{OrderBy: interfaces.OrderByCreatedAt})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, 1, len(users), "they should be equal")
+		assert.Equal(t, int64(1), total, "they should be equal")
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetAll)), "they should be equal")
+
+		_, _, _ = r.GetAll(ctx, interfaces.Filters{Limit: 2})
+		assert.Equal(t, 2, len(fake.CallsTo(repositories.MethodGetAll)), "they should be equal")
+	})
+
+	t.Run("writes invalidate pages", func(t *testing.T) {
+		fake := mocks.NewRecordingUsersRepo(fixtures.NewUser(fixtures.WithID(1)))
+		r := repositories.NewUserRepoCache(fake, cache.NewLRU(10), repositories.WithListTTL(time.Minute))
+
+		_, _, _ = r.GetAll(ctx, interfaces.Filters{})
+
+		user := fixtures.NewUser(fixtures.WithID(2))
+		if err := r.Create(ctx, &user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+
+		users, _, _ := r.GetAll(ctx, interfaces.Filters{})
+
+		assert.Equal(t, 2, len(users), "they should be equal")
+		assert.Equal(t, 2, len(fake.CallsTo(repositories.MethodGetAll)), "they should be equal")
+	})
+}
+
+// slowUsersRepo delays GetById to widen the window of concurrent misses.
+type slowUsersRepo struct {
+	*mocks.RecordingUsersRepo
+	delay time.Duration
+}
+
+func (s *slowUsersRepo) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	time.Sleep(s.delay)
+
+	return s.RecordingUsersRepo.GetById(ctx, id, opts...)
+}
+
+// pausedUsersRepo holds its first GetById, once the user is read, until
+// resume is closed.
+type pausedUsersRepo struct {
+	*mocks.RecordingUsersRepo
+	once   sync.Once
+	loaded chan struct{}
+	resume chan struct{}
+}
+
+func (p *pausedUsersRepo) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	user, err := p.RecordingUsersRepo.GetById(ctx, id, opts...)
+
+	p.once.Do(func() {
+		close(p.loaded)
+		<-p.resume
+	})
+
+	return user, err
+}
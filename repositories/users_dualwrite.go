@@ -0,0 +1,384 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+)
+
+const shadowTimeout = 10 * time.Second
+
+// Primary selects the backend the dual write repository reads from.
+type Primary int32
+
+const (
+	PrimarySource Primary = iota
+	PrimaryTarget
+)
+
+// WritePolicy decides what a write does when it was applied to the source
+// but failed on the target.
+type WritePolicy int32
+
+const (
+	// PolicyFail returns the error of the target, the write stays applied
+	// to the source.
+	PolicyFail WritePolicy = iota
+	// PolicyLog logs the error of the target and succeeds.
+	PolicyLog
+	// PolicyRepair hands the write to the repair queue and succeeds, unless
+	// the queue fails too.
+	PolicyRepair
+)
+
+// Repair is a write applied to the source that the target missed. The ids
+// must be copied again from the source to the target.
+type Repair struct {
+	Method string
+	IDs    []int64
+	Err    error
+}
+
+// ShadowDiff describes the differences between the result of a read on the
+// primary and on the other backend.
+type ShadowDiff struct {
+	Method string
+	Diffs  []string
+	Err    error
+}
+
+type dualWriteConfig struct {
+	primary Primary
+	policy  WritePolicy
+	shadow  bool
+	repair  func(context.Context, Repair) error
+	report  func(context.Context, ShadowDiff)
+}
+
+type DualWriteOption func(*dualWriteConfig)
+
+func defaultDualWriteConfig() dualWriteConfig {
+	return dualWriteConfig{
+		primary: PrimarySource,
+		policy:  PolicyFail,
+		repair: func(ctx context.Context, r Repair) error {
+			return errors.New("no repair queue configured")
+		},
+		report: func(ctx context.Context, d ShadowDiff) {
+			slog.WarnContext(ctx, "shadow read mismatch",
+				slog.String("method", d.Method),
+				slog.Any("diffs", d.Diffs),
+				slog.Any("error", d.Err),
+			)
+		},
+	}
+}
+
+func WithPrimary(primary Primary) DualWriteOption {
+	return func(c *dualWriteConfig) {
+		c.primary = primary
+	}
+}
+
+func WithWritePolicy(policy WritePolicy) DualWriteOption {
+	return func(c *dualWriteConfig) {
+		c.policy = policy
+	}
+}
+
+// WithShadowReads repeats every read on the other backend in the background
+// and reports the differences.
+func WithShadowReads(enabled bool) DualWriteOption {
+	return func(c *dualWriteConfig) {
+		c.shadow = enabled
+	}
+}
+
+// WithRepairQueue sets where PolicyRepair sends the writes the target missed.
+func WithRepairQueue(enqueue func(context.Context, Repair) error) DualWriteOption {
+	return func(c *dualWriteConfig) {
+		c.repair = enqueue
+	}
+}
+
+// WithShadowReport replaces the default report of the shadow reads, a warn log.
+func WithShadowReport(report func(context.Context, ShadowDiff)) DualWriteOption {
+	return func(c *dualWriteConfig) {
+		c.report = report
+	}
+}
+
+// UserRepoDualWrite writes every user to the source and then to the target,
+// and reads them from the primary, so a backend can be migrated to another
+// while both are in use. The source is always written first since it
+// assigns the ids of the created users, MySQL when migrating to Mongo. The
+// primary, the policy and the shadow reads can be changed at runtime.
+//
+// Writes given utils.WithTx or utils.WithLock only join the transaction on
+// the source, the target writes without them and a rollback does not undo
+// its write.
+type UserRepoDualWrite struct {
+	source  interfaces.UsersRepo
+	target  interfaces.UsersRepo
+	config  dualWriteConfig
+	primary atomic.Int32
+	policy  atomic.Int32
+	shadow  atomic.Bool
+}
+
+func NewUserRepoDualWrite(source, target interfaces.UsersRepo, opts ...DualWriteOption) *UserRepoDualWrite {
+	config := defaultDualWriteConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	r := &UserRepoDualWrite{source: source, target: target, config: config}
+	r.SetPrimary(config.primary)
+	r.SetWritePolicy(config.policy)
+	r.SetShadowReads(config.shadow)
+
+	return r
+}
+
+func (r *UserRepoDualWrite) SetPrimary(primary Primary) {
+	r.primary.Store(int32(primary))
+}
+
+func (r *UserRepoDualWrite) SetWritePolicy(policy WritePolicy) {
+	r.policy.Store(int32(policy))
+}
+
+func (r *UserRepoDualWrite) SetShadowReads(enabled bool) {
+	r.shadow.Store(enabled)
+}
+
+// reads returns the primary and the secondary backend.
+func (r *UserRepoDualWrite) reads() (interfaces.UsersRepo, interfaces.UsersRepo) {
+	if Primary(r.primary.Load()) == PrimaryTarget {
+		return r.target, r.source
+	}
+
+	return r.source, r.target
+}
+
+// shadowRead runs read on the secondary in the background when the shadow
+// reads are enabled and the primary read succeeded. The reads inside a
+// transaction are not repeated, it may be over by then. read must compare
+// copies of the results, the caller owns them once the read returns.
+func (r *UserRepoDualWrite) shadowRead(ctx context.Context, method string, err error, opts []utils.Options, read func(context.Context, interfaces.UsersRepo) ([]string, error)) {
+	if err != nil || !r.shadow.Load() || utils.HasTx(opts...) {
+		return
+	}
+
+	_, secondary := r.reads()
+
+	go func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, shadowTimeout)
+		defer cancel()
+
+		diffs, err := read(ctx, secondary)
+		if err != nil || len(diffs) > 0 {
+			r.config.report(ctx, ShadowDiff{Method: method, Diffs: diffs, Err: err})
+		}
+	}(context.WithoutCancel(ctx))
+}
+
+// written applies the policy to the result of a write on the target.
+func (r *UserRepoDualWrite) written(ctx context.Context, method string, ids []int64, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch WritePolicy(r.policy.Load()) {
+	case PolicyLog:
+		slog.ErrorContext(ctx, "dual write failed on target",
+			slog.String("method", method),
+			slog.Any("ids", ids),
+			slog.Any("error", err),
+		)
+		return nil
+	case PolicyRepair:
+		if rerr := r.config.repair(ctx, Repair{Method: method, IDs: ids, Err: err}); rerr != nil {
+			return fmt.Errorf("dual write %s: %w, enqueuing repair: %w", method, err, rerr)
+		}
+		return nil
+	default:
+		return fmt.Errorf("dual write %s applied to source only: %w", method, err)
+	}
+}
+
+func (r *UserRepoDualWrite) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	primary, _ := r.reads()
+	user, err := primary.GetById(ctx, id, opts...)
+	compared := copyUser(user)
+
+	r.shadowRead(ctx, MethodGetById, err, opts, func(ctx context.Context, secondary interfaces.UsersRepo) ([]string, error) {
+		shadow, err := secondary.GetById(ctx, id, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		return diffUsers(nonNil(compared), nonNil(shadow)), nil
+	})
+
+	return user, err
+}
+
+func (r *UserRepoDualWrite) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	primary, _ := r.reads()
+	users, total, err := primary.GetAll(ctx, filters, opts...)
+	compared := copyUsers(users)
+
+	r.shadowRead(ctx, MethodGetAll, err, opts, func(ctx context.Context, secondary interfaces.UsersRepo) ([]string, error) {
+		shadow, shadowTotal, err := secondary.GetAll(ctx, filters, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		diffs := diffUsers(compared, shadow)
+		if total != shadowTotal {
+			diffs = append(diffs, fmt.Sprintf("total: %d != %d", total, shadowTotal))
+		}
+
+		return diffs, nil
+	})
+
+	return users, total, err
+}
+
+func (r *UserRepoDualWrite) GetByIDs(ctx context.Context, ids []int64, opts ...utils.Options) ([]*interfaces.User, []int64, error) {
+	primary, _ := r.reads()
+	users, missing, err := primary.GetByIDs(ctx, ids, opts...)
+	compared := copyUsers(users)
+
+	r.shadowRead(ctx, MethodGetByIDs, err, opts, func(ctx context.Context, secondary interfaces.UsersRepo) ([]string, error) {
+		shadow, _, err := secondary.GetByIDs(ctx, ids, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		return diffUsers(compared, shadow), nil
+	})
+
+	return users, missing, err
+}
+
+func (r *UserRepoDualWrite) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	primary, _ := r.reads()
+	stats, err := primary.Stats(ctx, filters, query, opts...)
+	var total int64
+	if stats != nil {
+		total = stats.Total
+	}
+
+	r.shadowRead(ctx, MethodStats, err, opts, func(ctx context.Context, secondary interfaces.UsersRepo) ([]string, error) {
+		shadow, err := secondary.Stats(ctx, filters, query, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		if total != shadow.Total {
+			return []string{fmt.Sprintf("total: %d != %d", total, shadow.Total)}, nil
+		}
+
+		return nil, nil
+	})
+
+	return stats, err
+}
+
+func (r *UserRepoDualWrite) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	if err := r.source.Create(ctx, user, opts...); err != nil {
+		return err
+	}
+
+	return r.written(ctx, MethodCreate, []int64{int64(user.ID)}, r.target.Create(ctx, copyUser(user), withoutTx(opts)...))
+}
+
+func (r *UserRepoDualWrite) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	if err := r.source.Update(ctx, user, vals, opts...); err != nil {
+		return err
+	}
+
+	return r.written(ctx, MethodUpdate, []int64{int64(user.ID)}, r.target.Update(ctx, user, vals, withoutTx(opts)...))
+}
+
+func (r *UserRepoDualWrite) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	if err := r.source.Delete(ctx, ids, opts...); err != nil {
+		return err
+	}
+
+	return r.written(ctx, MethodDelete, ids, r.target.Delete(ctx, ids, withoutTx(opts)...))
+}
+
+// copyUsers copies the users, for the comparisons running after the caller
+// got them.
+func copyUsers(users []*interfaces.User) []*interfaces.User {
+	copies := make([]*interfaces.User, len(users))
+	for i, u := range users {
+		copies[i] = copyUser(u)
+	}
+
+	return copies
+}
+
+func nonNil(user *interfaces.User) []*interfaces.User {
+	if user == nil {
+		return nil
+	}
+
+	return []*interfaces.User{user}
+}
+
+// diffUsers lists the users missing from either side and the fields that
+// differ. The timestamps are compared to the second, the precision of the
+// MySQL columns.
+func diffUsers(primary, shadow []*interfaces.User) []string {
+	shadowByID := make(map[uint]*interfaces.User, len(shadow))
+	for _, u := range shadow {
+		shadowByID[u.ID] = u
+	}
+
+	diffs := []string{}
+	for _, u := range primary {
+		s, ok := shadowByID[u.ID]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("user %d: missing from shadow", u.ID))
+			continue
+		}
+		delete(shadowByID, u.ID)
+
+		if u.Name != s.Name {
+			diffs = append(diffs, fmt.Sprintf("user %d: name %q != %q", u.ID, u.Name, s.Name))
+		}
+		if u.Age != s.Age {
+			diffs = append(diffs, fmt.Sprintf("user %d: age %d != %d", u.ID, u.Age, s.Age))
+		}
+		if !sameSecond(u.CreatedAt, s.CreatedAt) {
+			diffs = append(diffs, fmt.Sprintf("user %d: created_at %s != %s", u.ID, u.CreatedAt, s.CreatedAt))
+		}
+		if !sameSecond(u.UpdatedAt, s.UpdatedAt) {
+			diffs = append(diffs, fmt.Sprintf("user %d: updated_at %s != %s", u.ID, u.UpdatedAt, s.UpdatedAt))
+		}
+	}
+
+	for _, s := range shadow {
+		if _, ok := shadowByID[s.ID]; ok {
+			diffs = append(diffs, fmt.Sprintf("user %d: missing from primary", s.ID))
+		}
+	}
+
+	return diffs
+}
+
+func sameSecond(a, b time.Time) bool {
+	d := a.Sub(b)
+
+	return d < time.Second && d > -time.Second
+}
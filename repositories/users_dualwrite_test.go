@@ -0,0 +1,142 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestUserRepoDualWrite(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("writes reach both backends", func(t *testing.T) {
+		source, target := mocks.NewRecordingUsersRepo(), mocks.NewRecordingUsersRepo()
+		r := repositories.NewUserRepoDualWrite(source, target)
+
+		user := &interfaces.User{Name: "john", Age: 20}
+		if err := r.Create(ctx, user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+
+		if err := r.Update(ctx, user, map[string]interface{}{"name": "jane"}); err != nil {
+			t.Fatalf("update user: %v", err)
+		}
+
+		copied, err := target.GetById(ctx, int64(user.ID))
+		assert.Nil(t, err, "should be nil")
+		assert.Equal(t, "jane", copied.Name, "they should be equal")
+
+		if err := r.Delete(ctx, []int64{int64(user.ID)}); err != nil {
+			t.Fatalf("delete user: %v", err)
+		}
+
+		copied, _ = target.GetById(ctx, int64(user.ID))
+		assert.Nil(t, copied, "should be nil")
+	})
+
+	t.Run("source failures do not reach the target", func(t *testing.T) {
+		source, target := mocks.NewRecordingUsersRepo(), mocks.NewRecordingUsersRepo()
+		refused := errors.New("connection refused")
+		source.Fail(refused)
+		r := repositories.NewUserRepoDualWrite(source, target)
+
+		err := r.Create(ctx, &interfaces.User{Name: "john"})
+		assert.Equal(t, refused, err, "they should be equal")
+		assert.Equal(t, 0, len(target.CallsTo(repositories.MethodCreate)), "they should be equal")
+	})
+
+	t.Run("target failures follow the policy", func(t *testing.T) {
+		source, target := mocks.NewRecordingUsersRepo(), mocks.NewRecordingUsersRepo()
+		refused := errors.New("connection refused")
+		target.Fail(refused)
+
+		repairs := []repositories.Repair{}
+		r := repositories.NewUserRepoDualWrite(source, target, repositories.WithRepairQueue(func(ctx context.Context, repair repositories.Repair) error {
+			repairs = append(repairs, repair)
+			return nil
+		}))
+
+		err := r.Create(ctx, &interfaces.User{Name: "john"})
+		assert.True(t, errors.Is(err, refused), "should be true")
+
+		r.SetWritePolicy(repositories.PolicyLog)
+		err = r.Update(ctx, &interfaces.User{ID: 1}, map[string]interface{}{"name": "jane"})
+		assert.Nil(t, err, "should be nil")
+
+		r.SetWritePolicy(repositories.PolicyRepair)
+		err = r.Delete(ctx, []int64{1})
+		assert.Nil(t, err, "should be nil")
+		assert.Equal(t, []repositories.Repair{{Method: repositories.MethodDelete, IDs: []int64{1}, Err: refused}}, repairs, "they should be equal")
+	})
+
+	t.Run("source transactions stay on the source", func(t *testing.T) {
+		source, target := mocks.NewRecordingUsersRepo(), mocks.NewRecordingUsersRepo()
+		r := repositories.NewUserRepoDualWrite(source, target)
+		tx := utils.WithTx(&gorm.DB{})
+
+		user := &interfaces.User{Name: "john"}
+		assert.Nil(t, r.Create(ctx, user, tx, utils.WithLock), "should be nil")
+		assert.Nil(t, r.Update(ctx, user, map[string]interface{}{"name": "jane"}, tx), "should be nil")
+		assert.Nil(t, r.Delete(ctx, []int64{int64(user.ID)}, tx), "should be nil")
+
+		for _, c := range source.Calls() {
+			assert.True(t, utils.HasTx(c.Opts...), "should be true")
+		}
+
+		assert.Equal(t, []string{repositories.MethodCreate, repositories.MethodUpdate, repositories.MethodDelete}, target.Methods(), "they should be equal")
+		for _, c := range target.Calls() {
+			assert.True(t, utils.EqualOptions(c.Opts, nil), "should be true")
+		}
+	})
+
+	t.Run("reads come from the primary", func(t *testing.T) {
+		source := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "source"})
+		target := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "target"})
+		r := repositories.NewUserRepoDualWrite(source, target)
+
+		user, _ := r.GetById(ctx, 1)
+		assert.Equal(t, "source", user.Name, "they should be equal")
+
+		r.SetPrimary(repositories.PrimaryTarget)
+		user, _ = r.GetById(ctx, 1)
+		assert.Equal(t, "target", user.Name, "they should be equal")
+	})
+
+	t.Run("shadow reads report the differences", func(t *testing.T) {
+		now := time.Now()
+		source := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "john", CreatedAt: now}, interfaces.User{ID: 2, Name: "jane"})
+		target := mocks.NewRecordingUsersRepo(interfaces.User{ID: 1, Name: "johnny", CreatedAt: now.Add(time.Millisecond)})
+
+		reports := make(chan repositories.ShadowDiff, 1)
+		r := repositories.NewUserRepoDualWrite(source, target,
+			repositories.WithShadowReads(true),
+			repositories.WithShadowReport(func(ctx context.Context, d repositories.ShadowDiff) {
+				reports <- d
+			}),
+		)
+
+		if _, _, err := r.GetByIDs(ctx, []int64{1, 2}); err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		select {
+		case d := <-reports:
+			assert.Equal(t, repositories.MethodGetByIDs, d.Method, "they should be equal")
+			assert.Equal(t, []string{
+				`user 1: name "john" != "johnny"`,
+				"user 2: missing from shadow",
+			}, d.Diffs, "they should be equal")
+		case <-time.After(time.Second):
+			t.Fatal("expected a shadow diff")
+		}
+	})
+}
@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"repos/interfaces"
@@ -12,118 +14,219 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// userRepoMongo is the generic mongo repository instantiated for the users,
+// with the filters and the statistics of the users on top.
 type userRepoMongo struct {
-	collection *mongo.Collection
+	mongoRepo[interfaces.User, int64]
 }
 
-func NewUserRepoMongo(collection *mongo.Database) interfaces.UsersRepo {
-	return &userRepoMongo{collection: collection.Collection("users")}
+func NewUserRepoMongo(db *mongo.Database) interfaces.UsersRepo {
+	return &userRepoMongo{mongoRepo[interfaces.User, int64]{
+		collection: db.Collection(interfaces.UserEntity.Table),
+		entity:     interfaces.UserEntity,
+	}}
 }
 
-func (r userRepoMongo) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
-	var user *interfaces.User
-	if err := r.collection.FindOne(ctx, bson.D{{"id", id}}).Decode(&user); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-
-		return nil, err
+func (r userRepoMongo) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	query, err := filters.Query()
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return user, nil
+	return r.mongoRepo.GetAll(ctx, query, opts...)
 }
 
-func (r userRepoMongo) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+// ExplainGetAll returns the executionStats explain of the find run by GetAll.
+func (r userRepoMongo) ExplainGetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) (string, error) {
+	query, err := filters.Query()
+	if err != nil {
+		return "", err
+	}
 
-	var limit int64 = utils.Limit
-	if filters.Limit != 0 {
-		limit = int64(filters.Limit)
+	fields, err := interfaces.SelectedFields(opts...)
+	if err != nil {
+		return "", err
 	}
 
-	var offset int64 = 0
-	if filters.Offset != 0 {
-		offset = int64(filters.Offset)
+	find := r.findOptions(query, fields)
+
+	command := bson.D{
+		{"find", r.collection.Name()},
+		{"filter", r.filter(query)},
 	}
 
-	var sort = "createdat"
-	if filters.OrderBy != "" {
-		sort = string(filters.OrderBy)
+	if find.Sort != nil {
+		command = append(command,
+			bson.E{Key: "sort", Value: find.Sort},
+			bson.E{Key: "skip", Value: *find.Skip},
+			bson.E{Key: "limit", Value: *find.Limit},
+		)
 	}
 
-	options := options.FindOptions{
-		Skip:  &offset,
-		Limit: &limit,
-		Sort:  bson.D{{sort, -1}},
+	if find.Projection != nil {
+		command = append(command, bson.E{Key: "projection", Value: find.Projection})
 	}
 
-	f := bson.A{}
+	var plan bson.Raw
+	err = r.collection.Database().
+		RunCommand(ctx, bson.D{{"explain", command}, {"verbosity", "executionStats"}}).
+		Decode(&plan)
+	if err != nil {
+		return "", err
+	}
+
+	return plan.String(), nil
+}
 
-	now := time.Date(2024, 4, 17, 0, 0, 0, 0, time.Local)
+func (r userRepoMongo) MaxID(ctx context.Context) (int64, error) {
+	findOptions := options.FindOne().SetSort(bson.D{{"id", -1}}).SetProjection(bson.D{{"id", 1}})
+
+	var user interfaces.User
+	if err := r.collection.FindOne(ctx, bson.D{}, findOptions).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
 
-	var createdAtGte time.Time = now.AddDate(0, 0, -30)
-	if !filters.CreatedAtGte.IsZero() {
-		createdAtGte = filters.CreatedAtGte
+		return 0, err
 	}
 
-	var createdAtLte time.Time = now
-	if !filters.CreatedAtLte.IsZero() {
-		createdAtLte = filters.CreatedAtLte
+	return int64(user.ID), nil
+}
+
+func (r userRepoMongo) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	match, err := filters.Query()
+	if err != nil {
+		return nil, err
 	}
 
-	f = append(f, bson.D{{"createdat", bson.D{{"$lte", createdAtLte}}}})
-	f = append(f, bson.D{{"createdat", bson.D{{"$gt", createdAtGte}}}})
+	query, err = query.Normalize()
+	if err != nil {
+		return nil, err
+	}
 
-	if filters.AgeGte != 0 {
-		f = append(f, bson.D{{"age", bson.D{{"$gte", filters.AgeGte}}}})
+	boundaries := bson.A{0}
+	for _, b := range query.AgeBoundaries {
+		boundaries = append(boundaries, b)
 	}
+	boundaries = append(boundaries, 256)
 
-	if filters.AgeLte != 0 {
-		f = append(f, bson.D{{"age", bson.D{{"$lte", filters.AgeLte}}}})
+	period := bson.D{{"date", "$createdat"}, {"unit", string(query.Interval)}, {"timezone", "UTC"}}
+	if query.Interval == interfaces.IntervalWeek {
+		period = append(period, bson.E{Key: "startOfWeek", Value: "monday"})
 	}
 
-	filter := bson.D{{"$and", f}}
+	pipeline := mongo.Pipeline{
+		{{"$match", r.filter(match)}},
+		{{"$facet", bson.D{
+			{"totals", bson.A{
+				bson.D{{"$group", bson.D{
+					{"_id", nil},
+					{"total", bson.D{{"$sum", 1}}},
+					{"averageage", bson.D{{"$avg", "$age"}}},
+				}}},
+			}},
+			{"ages", bson.A{
+				bson.D{{"$bucket", bson.D{
+					{"groupBy", "$age"},
+					{"boundaries", boundaries},
+					{"output", bson.D{{"count", bson.D{{"$sum", 1}}}}},
+				}}},
+			}},
+			{"signups", bson.A{
+				bson.D{{"$group", bson.D{
+					{"_id", bson.D{{"$dateTrunc", period}}},
+					{"count", bson.D{{"$sum", 1}}},
+				}}},
+				bson.D{{"$sort", bson.D{{"_id", 1}}}},
+			}},
+		}}},
+	}
 
-	cursor, err := r.collection.Find(ctx, filter, &options)
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, 0, nil
+		return nil, err
+	}
+
+	var result []struct {
+		Totals []struct {
+			Total      int64
+			AverageAge float64
+		}
+		Ages []struct {
+			ID    int `bson:"_id"`
+			Count int64
 		}
+		SignUps []struct {
+			ID    time.Time `bson:"_id"`
+			Count int64
+		}
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
 
-		return nil, 0, err
+	stats := &interfaces.UserStats{AgeBuckets: query.AgeBuckets()}
+	if len(result) == 0 {
+		return stats, nil
 	}
 
-	var users []*interfaces.User
-	if err = cursor.All(ctx, &users); err != nil {
-		return nil, 0, err
+	if len(result[0].Totals) > 0 {
+		stats.Total = result[0].Totals[0].Total
+		stats.AverageAge = result[0].Totals[0].AverageAge
 	}
 
-	return users, 0, nil
-}
+	for _, a := range result[0].Ages {
+		for i, b := range stats.AgeBuckets {
+			if b.Min == a.ID {
+				stats.AgeBuckets[i].Count = a.Count
+			}
+		}
+	}
 
-func (r userRepoMongo) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
-	_, err := r.collection.InsertOne(ctx, user)
+	for _, s := range result[0].SignUps {
+		stats.SignUps = append(stats.SignUps, interfaces.SignUps{Period: s.ID, Count: s.Count})
+	}
 
-	return err
+	return stats, nil
 }
 
-func (r userRepoMongo) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
-	updates := bson.D{}
-	for k, v := range vals {
-		update := bson.E{Key: k, Value: v}
-		updates = append(updates, update)
-	}
-
-	updateFilter := bson.D{{"$set", updates}}
+// mongoField maps a column to the bson key the driver stores it under.
+func mongoField(field string) string {
+	return strings.ReplaceAll(field, "_", "")
+}
 
-	if _, err := r.collection.UpdateOne(ctx, bson.D{{"id", user.ID}}, updateFilter); err != nil {
-		return err
+func mongoProjection(fields []string) bson.D {
+	projection := bson.D{}
+	for _, field := range fields {
+		projection = append(projection, bson.E{Key: mongoField(field), Value: 1})
 	}
 
-	return nil
+	return projection
 }
 
-func (r userRepoMongo) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
-	_, err := r.collection.DeleteMany(ctx, bson.M{"id": bson.M{"$in": ids}})
+// mongoSteppedDown are the codes of the errors raised while the primary
+// changes: NotWritablePrimary, NotPrimaryNoSecondaryOk, NotPrimaryOrSecondary,
+// PrimarySteppedDown and ShutdownInProgress.
+var mongoSteppedDown = []int{10107, 13435, 13436, 189, 91}
+
+// IsTransientMongo reports whether err is worth retrying: errors labelled
+// as retryable or transient by the server, primary step-downs and network errors.
+func IsTransientMongo(err error) bool {
+	var labeled mongo.LabeledError
+	if errors.As(err, &labeled) {
+		if labeled.HasErrorLabel("RetryableWriteError") || labeled.HasErrorLabel("TransientTransactionError") {
+			return true
+		}
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		for _, code := range mongoSteppedDown {
+			if serverErr.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
 
-	return err
+	return mongo.IsNetworkError(err)
 }
@@ -2,166 +2,44 @@ package repositories_test
 
 import (
 	"context"
-	"log"
 	"testing"
 	"time"
 
+	"repos/fixtures"
 	"repos/interfaces"
 	"repos/repositories"
+	"repos/utils"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/modules/mongodb"
-	testMysql "github.com/testcontainers/testcontainers-go/modules/mysql"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-type testContainerMongo struct {
-	container *testMysql.MySQLContainer
-}
-
-func NewTestContainerMongo(ctx context.Context) (testContainerMongo, func(ctx context.Context), error) {
-	container, err := testMysql.RunContainer(ctx,
-		testcontainers.WithImage("mongo:7.0.5"),
-		testMysql.WithScripts("main.sql"),
-	)
-
-	t := testContainerMongo{container: container}
-
-	return t, t.cleanDB, err
-}
-
-func (tcm testContainerMongo) GetConnection(ctx context.Context) string {
-	return tcm.container.MustConnectionString(ctx) + "?charset=utf8mb4&parseTime=True&loc=Local"
-}
-
-func (tcm testContainerMongo) cleanDB(ctx context.Context) {
-	if err := tcm.container.Terminate(ctx); err != nil {
-		log.Fatalf("failed to terminate container: %s", err)
-	}
-}
-
 func TestUserMongoRepoGetByID(t *testing.T) {
-	ctx := context.Background()
+	t.Parallel()
 
-	mongodbContainer, err := mongodb.Run(ctx, "mongo:7.0.5")
-	if err != nil {
-		log.Fatalf("failed to start container: %s", err)
-	}
-
-	defer func() {
-		if err := mongodbContainer.Terminate(ctx); err != nil {
-			log.Fatalf("failed to terminate container: %s", err)
-		}
-	}()
-
-	uri, err := mongodbContainer.ConnectionString(ctx)
-	if err != nil {
-		log.Fatalf("failed to terminate container: %s", err)
-	}
-
-	mongo, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
-	if err != nil {
-		panic(err)
-	}
-
-	if err = mongo.Ping(ctx, readpref.Primary()); err != nil {
-		panic(err)
-	}
+	ctx := context.Background()
 
-	db := mongo.Database("test")
-
-	collection := db.Collection("users")
-	if _, err := collection.InsertOne(ctx, interfaces.User{ID: 1, Name: "asdsa", Age: 12}); err != nil {
-		panic(err)
-	}
+	db := newTestMongo(t)
 
 	r := repositories.NewUserRepoMongo(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
 
 	user, err := r.GetById(ctx, 1)
 	if err != nil {
 		t.Fatalf("creating user table: %v", err)
 	}
 
-	assert.Equal(t, "asdsa", user.Name, "they should be equal")
+	assert.Equal(t, "first", user.Name, "they should be equal")
 }
 
 func TestUserMongoRepoGetAll(t *testing.T) {
-	ctx := context.Background()
+	t.Parallel()
 
-	mongodbContainer, err := mongodb.Run(ctx, "mongo:7.0.5")
-	if err != nil {
-		log.Fatalf("failed to start container: %s", err)
-	}
-
-	defer func() {
-		if err := mongodbContainer.Terminate(ctx); err != nil {
-			log.Fatalf("failed to terminate container: %s", err)
-		}
-	}()
-
-	uri, err := mongodbContainer.ConnectionString(ctx)
-	if err != nil {
-		log.Fatalf("failed to terminate container: %s", err)
-	}
-
-	mongo, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
-	if err != nil {
-		panic(err)
-	}
-
-	if err = mongo.Ping(ctx, readpref.Primary()); err != nil {
-		panic(err)
-	}
+	ctx := context.Background()
 
-	db := mongo.Database("test")
+	db := newTestMongo(t)
 
 	r := repositories.NewUserRepoMongo(db)
-	collection := db.Collection("users")
-
-	docs := []interface{}{
-		interfaces.User{
-			ID:        1,
-			Name:      "first",
-			Age:       55,
-			CreatedAt: time.Date(2024, 4, 10, 23, 0, 0, 0, time.Local),
-		},
-		interfaces.User{
-			ID:        2,
-			Name:      "second",
-			Age:       22,
-			CreatedAt: time.Date(2024, 4, 11, 23, 0, 0, 0, time.Local),
-		},
-		interfaces.User{
-			ID:        3,
-			Name:      "third",
-			Age:       40,
-			CreatedAt: time.Date(2024, 4, 12, 23, 0, 0, 0, time.Local),
-		},
-		interfaces.User{
-			ID:        4,
-			Name:      "forth",
-			Age:       30,
-			CreatedAt: time.Date(2024, 4, 13, 23, 0, 0, 0, time.Local),
-		},
-		interfaces.User{
-			ID:        5,
-			Name:      "five",
-			Age:       45,
-			CreatedAt: time.Date(2024, 4, 14, 23, 0, 0, 0, time.Local),
-		},
-		interfaces.User{
-			ID:        6,
-			Name:      "six",
-			Age:       66,
-			CreatedAt: time.Date(2024, 4, 15, 23, 0, 0, 0, time.Local),
-		},
-	}
-	if _, err := collection.InsertMany(ctx, docs); err != nil {
-		panic(err)
-	}
+	fixtures.Setup(t, r, fixtures.Users()...)
 
 	t.Run("check limit to 2", func(t *testing.T) {
 		users, _, err := r.GetAll(ctx, interfaces.Filters{Offset: 0, Limit: 2})
@@ -279,35 +157,161 @@ func TestUserMongoRepoGetAll(t *testing.T) {
 	})
 }
 
-func TestUserMongoRepoCreate(t *testing.T) {
+func TestUserMongoRepoStats(t *testing.T) {
+	t.Parallel()
+
 	ctx := context.Background()
 
-	mongodbContainer, err := mongodb.Run(ctx, "mongo:7.0.5")
-	if err != nil {
-		log.Fatalf("failed to start container: %s", err)
+	db := newTestMongo(t)
+
+	r := repositories.NewUserRepoMongo(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	t.Run("per day", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{}, interfaces.StatsQuery{})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, int64(6), stats.Total, "they should be equal")
+		assert.Equal(t, float64(43), stats.AverageAge, "they should be equal")
+		assert.Equal(t, 7, len(stats.AgeBuckets), "they should be equal")
+		assert.Equal(t, int64(0), stats.AgeBuckets[0].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[1].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[6].Count, "they should be equal")
+		assert.Equal(t, 6, len(stats.SignUps), "they should be equal")
+	})
+
+	t.Run("per week", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{}, interfaces.StatsQuery{Interval: interfaces.IntervalWeek})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, 2, len(stats.SignUps), "they should be equal")
+		assert.Equal(t, int64(5), stats.SignUps[0].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.SignUps[1].Count, "they should be equal")
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{AgeGte: 22, AgeLte: 45}, interfaces.StatsQuery{
+			AgeBoundaries: []uint8{35},
+		})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, int64(4), stats.Total, "they should be equal")
+		assert.Equal(t, int64(2), stats.AgeBuckets[0].Count, "they should be equal")
+		assert.Equal(t, int64(2), stats.AgeBuckets[1].Count, "they should be equal")
+	})
+
+	t.Run("utc days", func(t *testing.T) {
+		late := time.Date(2024, 4, 10, 1, 0, 0, 0, time.FixedZone("+05:00", 5*60*60))
+		if err := r.Create(ctx, &interfaces.User{ID: 100, Name: "late", Age: 30, CreatedAt: late, UpdatedAt: late}); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+
+		stats, err := r.Stats(ctx, interfaces.Filters{IDs: []int64{100}}, interfaces.StatsQuery{})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		if len(stats.SignUps) != 1 {
+			t.Fatalf("expected 1 period, got %d", len(stats.SignUps))
+		}
+
+		assert.Equal(t, "2024-04-09", stats.SignUps[0].Period.UTC().Format("2006-01-02"), "they should be equal")
+	})
+}
+
+func TestUserMongoRepoFields(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMongo(t)
+
+	collection := db.Collection("users")
+	u := interfaces.User{ID: 1, Name: "asdsa", Age: 12, CreatedAt: time.Date(2024, 4, 15, 23, 0, 0, 0, time.Local)}
+	if _, err := collection.InsertOne(ctx, u); err != nil {
+		panic(err)
 	}
 
-	defer func() {
-		if err := mongodbContainer.Terminate(ctx); err != nil {
-			log.Fatalf("failed to terminate container: %s", err)
+	r := repositories.NewUserRepoMongo(db)
+	fields := utils.WithFields(interfaces.FieldID, interfaces.FieldName)
+
+	t.Run("get by id", func(t *testing.T) {
+		user, err := r.GetById(ctx, 1, fields)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Equal(t, "asdsa", user.Name, "they should be equal")
+		assert.Equal(t, uint8(0), user.Age, "they should be equal")
+	})
+
+	t.Run("get all", func(t *testing.T) {
+		users, _, err := r.GetAll(ctx, interfaces.Filters{}, fields)
+		if err != nil {
+			t.Fatalf("get users: %v", err)
 		}
-	}()
 
-	uri, err := mongodbContainer.ConnectionString(ctx)
+		assert.Equal(t, uint(1), users[0].ID, "they should be equal")
+		assert.True(t, users[0].CreatedAt.IsZero(), "they should be equal")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := r.GetById(ctx, 1, utils.WithFields("password"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestUserMongoRepoGetByIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMongo(t)
+
+	r := repositories.NewUserRepoMongo(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	users, missing, err := r.GetByIDs(ctx, []int64{5, 42, 2}, utils.WithFields(interfaces.FieldName))
 	if err != nil {
-		log.Fatalf("failed to terminate container: %s", err)
+		t.Fatalf("get users: %v", err)
 	}
 
-	mongo, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	assert.Equal(t, 2, len(users), "they should be equal")
+	assert.Equal(t, uint(5), users[0].ID, "they should be equal")
+	assert.Equal(t, "second", users[1].Name, "they should be equal")
+	assert.Equal(t, []int64{42}, missing, "they should be equal")
+}
+
+func TestUserMongoRepoExplainGetAll(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMongo(t)
+
+	r := repositories.NewUserRepoMongo(db)
+
+	plan, err := r.(interfaces.Explainer).ExplainGetAll(ctx, interfaces.Filters{AgeGte: 22})
 	if err != nil {
-		panic(err)
+		t.Fatalf("explaining users: %v", err)
 	}
 
-	if err = mongo.Ping(ctx, readpref.Primary()); err != nil {
-		panic(err)
-	}
+	assert.Contains(t, plan, "executionStats", "they should be equal")
+}
+
+func TestUserMongoRepoCreate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
 
-	db := mongo.Database("test")
+	db := newTestMongo(t)
 
 	r := repositories.NewUserRepoMongo(db)
 
@@ -324,34 +328,11 @@ func TestUserMongoRepoCreate(t *testing.T) {
 }
 
 func TestUserMongoRepoDelete(t *testing.T) {
-	ctx := context.Background()
+	t.Parallel()
 
-	mongodbContainer, err := mongodb.Run(ctx, "mongo:7.0.5")
-	if err != nil {
-		log.Fatalf("failed to start container: %s", err)
-	}
-
-	defer func() {
-		if err := mongodbContainer.Terminate(ctx); err != nil {
-			log.Fatalf("failed to terminate container: %s", err)
-		}
-	}()
-
-	uri, err := mongodbContainer.ConnectionString(ctx)
-	if err != nil {
-		log.Fatalf("failed to terminate container: %s", err)
-	}
-
-	mongo, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
-	if err != nil {
-		panic(err)
-	}
-
-	if err = mongo.Ping(ctx, readpref.Primary()); err != nil {
-		panic(err)
-	}
+	ctx := context.Background()
 
-	db := mongo.Database("test")
+	db := newTestMongo(t)
 
 	collection := db.Collection("users")
 	if _, err := collection.InsertOne(ctx, interfaces.User{ID: 1, Name: "asdsa", Age: 12}); err != nil {
@@ -403,34 +384,11 @@ func TestUserMongoRepoDelete(t *testing.T) {
 }
 
 func TestUserMongoRepoUpdate(t *testing.T) {
-	ctx := context.Background()
-
-	mongodbContainer, err := mongodb.Run(ctx, "mongo:7.0.5")
-	if err != nil {
-		log.Fatalf("failed to start container: %s", err)
-	}
-
-	defer func() {
-		if err := mongodbContainer.Terminate(ctx); err != nil {
-			log.Fatalf("failed to terminate container: %s", err)
-		}
-	}()
-
-	uri, err := mongodbContainer.ConnectionString(ctx)
-	if err != nil {
-		log.Fatalf("failed to terminate container: %s", err)
-	}
-
-	mongo, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
-	if err != nil {
-		panic(err)
-	}
+	t.Parallel()
 
-	if err = mongo.Ping(ctx, readpref.Primary()); err != nil {
-		panic(err)
-	}
+	ctx := context.Background()
 
-	db := mongo.Database("test")
+	db := newTestMongo(t)
 	collection := db.Collection("users")
 
 	u := interfaces.User{ID: 1, Name: "asdsa", Age: 12}
@@ -455,3 +413,30 @@ func TestUserMongoRepoUpdate(t *testing.T) {
 
 	assert.Equal(t, "new name", user.Name, "they should be equal")
 }
+
+func TestUserMongoRepoMaxID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMongo(t)
+
+	r := repositories.NewUserRepoMongo(db)
+
+	id, err := r.(interfaces.MaxIDer).MaxID(ctx)
+	if err != nil {
+		t.Fatalf("getting max id: %v", err)
+	}
+	assert.Equal(t, int64(0), id, "they should be equal")
+
+	collection := db.Collection("users")
+	if _, err := collection.InsertMany(ctx, []interface{}{interfaces.User{ID: 3}, interfaces.User{ID: 7}, interfaces.User{ID: 5}}); err != nil {
+		panic(err)
+	}
+
+	id, err = r.(interfaces.MaxIDer).MaxID(ctx)
+	if err != nil {
+		t.Fatalf("getting max id: %v", err)
+	}
+	assert.Equal(t, int64(7), id, "they should be equal")
+}
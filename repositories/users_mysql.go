@@ -2,106 +2,166 @@ package repositories
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
 	"fmt"
-	"time"
 
 	"repos/interfaces"
 	"repos/utils"
 
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 )
 
+const (
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+)
+
+// userRepoMysql is the generic gorm repository instantiated for the users,
+// with the filters and the statistics of the users on top.
 type userRepoMysql struct {
-	db *gorm.DB
+	gormRepo[interfaces.User, int64]
 }
 
 func NewUserRepoMysql(db *gorm.DB) interfaces.UsersRepo {
-	return &userRepoMysql{db: db}
+	return &userRepoMysql{gormRepo[interfaces.User, int64]{db: db, dialect: mysqlDialect, entity: interfaces.UserEntity}}
 }
 
-func (r userRepoMysql) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
-	var user *interfaces.User
-	err := utils.ConfigureDB(r.db, opts...).WithContext(ctx).Find(&user, id).Error
-	return user, err
+func (r userRepoMysql) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	query, err := filters.Query()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.gormRepo.GetAll(ctx, query, opts...)
 }
 
-func (r userRepoMysql) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+// ExplainGetAll returns the EXPLAIN FORMAT=JSON of the query run by GetAll.
+func (r userRepoMysql) ExplainGetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) (string, error) {
+	q, err := compileFilters(r.dialect, filters)
+	if err != nil {
+		return "", err
+	}
+
 	var users []*interfaces.User
+	stmt := q.Query(ctx, r.db, opts...).
+		Scopes(q.Page).
+		Session(&gorm.Session{DryRun: true}).
+		Find(&users).
+		Statement
+
+	var plan string
+	err = r.db.
+		WithContext(ctx).
+		Raw("EXPLAIN FORMAT=JSON "+stmt.SQL.String(), stmt.Vars...).
+		Row().
+		Scan(&plan)
+
+	return plan, err
+}
 
-	var offset = 0
-	if filters.Offset != 0 {
-		offset = filters.Offset
+func (r userRepoMysql) MaxID(ctx context.Context) (int64, error) {
+	var id *int64
+	err := r.db.WithContext(ctx).Model(&interfaces.User{}).Select("MAX(id)").Scan(&id).Error
+	if err != nil || id == nil {
+		return 0, err
 	}
 
-	var limit = utils.Limit
-	if filters.Limit != 0 {
-		limit = filters.Limit
+	return *id, nil
+}
+
+func (r userRepoMysql) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	q, err := compileFilters(r.dialect, filters)
+	if err != nil {
+		return nil, err
 	}
 
-	var orderBy = interfaces.OrderByCreatedAt
-	if filters.OrderBy != "" {
-		orderBy = filters.OrderBy
+	query, err = query.Normalize()
+	if err != nil {
+		return nil, err
 	}
 
-	now := time.Date(2024, 4, 17, 0, 0, 0, 0, time.Local)
+	stats := &interfaces.UserStats{AgeBuckets: query.AgeBuckets()}
 
-	var createdAtGte time.Time = now.AddDate(0, 0, -30)
-	if !filters.CreatedAtGte.IsZero() {
-		createdAtGte = filters.CreatedAtGte
+	var totals struct {
+		Total      int64
+		AverageAge float64
 	}
 
-	var createdAtLte time.Time = now
-	if !filters.CreatedAtLte.IsZero() {
-		createdAtLte = filters.CreatedAtLte
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select("COUNT(*) AS total, COALESCE(AVG(age), 0) AS average_age").
+		Scan(&totals).
+		Error
+	if err != nil {
+		return nil, err
 	}
 
-	stmp := utils.
-		ConfigureDB(r.db, opts...).
-		WithContext(ctx).
-		Debug().
-		Where("created_at <= ?", createdAtLte).
-		Where("created_at >= ?", createdAtGte)
+	stats.Total, stats.AverageAge = totals.Total, totals.AverageAge
 
-	if filters.AgeGte != 0 {
-		stmp = stmp.Where("age >= ?", filters.AgeGte)
-	}
+	bucket, args := ageBucketCase(query.AgeBoundaries)
 
-	if filters.AgeLte != 0 {
-		stmp = stmp.Where("age <= ?", filters.AgeLte)
+	var ages []struct {
+		Bucket int
+		Count  int64
 	}
 
-	var (
-		total = int64(len(filters.IDs))
-		err   error
-	)
-	if len(filters.IDs) > 0 {
-		err = stmp.Find(&users, filters.IDs).Error
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select("("+bucket+") AS bucket, COUNT(*) AS count", args...).
+		Group("bucket").
+		Scan(&ages).
+		Error
+	if err != nil {
+		return nil, err
+	}
 
-		return users, total, err
+	for _, a := range ages {
+		stats.AgeBuckets[a.Bucket].Count = a.Count
 	}
 
-	if err := stmp.Model(&interfaces.User{}).Count(&total).Error; err != nil {
-		return users, 0, err
+	// The periods are UTC days, as in Mongo, whatever the time zone of the
+	// session: the date is taken from the seconds since the epoch.
+	day := "DATE('1970-01-01' + INTERVAL UNIX_TIMESTAMP(created_at) SECOND)"
+	period := day
+	if query.Interval == interfaces.IntervalWeek {
+		period = "DATE_SUB(" + day + ", INTERVAL WEEKDAY(" + day + ") DAY)"
 	}
 
-	err = stmp.
-		Limit(limit).
-		Offset(offset).
-		Order(fmt.Sprintf("%v DESC", orderBy)).
-		Find(&users).
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select(period + " AS period, COUNT(*) AS count").
+		Group("period").
+		Order("period").
+		Scan(&stats.SignUps).
 		Error
+	if err != nil {
+		return nil, err
+	}
 
-	return users, total, err
+	return stats, nil
 }
 
-func (r userRepoMysql) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
-	return utils.ConfigureDB(r.db, opts...).WithContext(ctx).Create(user).Error
-}
+// ageBucketCase builds a CASE expression returning the index of the age bucket of each row.
+func ageBucketCase(boundaries []uint8) (string, []interface{}) {
+	expr := "CASE"
+	args := []interface{}{}
+	for i := len(boundaries) - 1; i >= 0; i-- {
+		expr += fmt.Sprintf(" WHEN age >= ? THEN %d", i+1)
+		args = append(args, boundaries[i])
+	}
 
-func (r userRepoMysql) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
-	return utils.ConfigureDB(r.db, opts...).WithContext(ctx).Model(&user).Updates(vals).Error
+	return expr + " ELSE 0 END", args
 }
 
-func (r userRepoMysql) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
-	return utils.ConfigureDB(r.db, opts...).WithContext(ctx).Delete(&interfaces.User{}, ids).Error
+// IsTransientMysql reports whether err is worth retrying: deadlocks, lock
+// wait timeouts and dropped connections.
+func IsTransientMysql(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn)
 }
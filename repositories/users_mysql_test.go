@@ -2,68 +2,29 @@ package repositories_test
 
 import (
 	"context"
-	"log"
-	"runtime"
 	"testing"
 	"time"
 
+	"repos/fixtures"
 	"repos/interfaces"
 	"repos/repositories"
+	"repos/utils"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/testcontainers/testcontainers-go"
-	testMysql "github.com/testcontainers/testcontainers-go/modules/mysql"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-type testContainerMysql struct {
-	mysqlContainer *testMysql.MySQLContainer
-}
-
-func NewTestContainerMysql(ctx context.Context) (testContainerMysql, func(ctx context.Context), error) {
-	image := "mysql:8.0"
-	if runtime.GOARCH == "arm64" {
-		image = "arm64v8/mysql:8.0"
-	}
-
-	mysqlContainer, err := testMysql.RunContainer(ctx,
-		testcontainers.WithImage(image),
-		testMysql.WithScripts("main.sql"),
-	)
-
-	t := testContainerMysql{mysqlContainer: mysqlContainer}
-
-	return t, t.cleanDB, err
-}
-
-func (tcm testContainerMysql) GetConnection(ctx context.Context) string {
-	return tcm.mysqlContainer.MustConnectionString(ctx) + "?charset=utf8mb4&parseTime=True&loc=Local"
-}
-
-func (tcm testContainerMysql) cleanDB(ctx context.Context) {
-	if err := tcm.mysqlContainer.Terminate(ctx); err != nil {
-		log.Fatalf("failed to terminate container: %s", err)
-	}
-}
-
 func TestUserMysqlRepoGetByID(t *testing.T) {
-	ctx := context.Background()
+	t.Parallel()
 
-	mysqlContainer, close, err := NewTestContainerMysql(ctx)
-	if err != nil {
-		t.Fatalf("mounting db container: %v", err)
-	}
-	defer close(ctx)
+	ctx := context.Background()
 
-	db, err := gorm.Open(mysql.Open(mysqlContainer.GetConnection(ctx)), &gorm.Config{
+	db := newTestMysql(t, &gorm.Config{
 		QueryFields: true,
 	})
-	if err != nil {
-		t.Fatalf("mounting db: %v", err)
-	}
 
 	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
 
 	user, err := r.GetById(ctx, 1)
 	if err != nil {
@@ -74,20 +35,14 @@ func TestUserMysqlRepoGetByID(t *testing.T) {
 }
 
 func TestUserMysqlRepoGetAll(t *testing.T) {
-	ctx := context.Background()
+	t.Parallel()
 
-	mysqlContainer, close, err := NewTestContainerMysql(ctx)
-	if err != nil {
-		t.Fatalf("mounting db container: %v", err)
-	}
-	defer close(ctx)
+	ctx := context.Background()
 
-	db, err := gorm.Open(mysql.Open(mysqlContainer.GetConnection(ctx)), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("mounting db: %v", err)
-	}
+	db := newTestMysql(t, &gorm.Config{})
 
 	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
 
 	t.Run("check limit to 2", func(t *testing.T) {
 		users, total, err := r.GetAll(ctx, interfaces.Filters{Offset: 0, Limit: 2})
@@ -243,22 +198,16 @@ func TestUserMysqlRepoGetAll(t *testing.T) {
 }
 
 func TestUserMysqlRepoCreate(t *testing.T) {
-	ctx := context.Background()
+	t.Parallel()
 
-	mysqlContainer, close, err := NewTestContainerMysql(ctx)
-	if err != nil {
-		t.Fatalf("mounting db container: %v", err)
-	}
-	defer close(ctx)
+	ctx := context.Background()
 
-	db, err := gorm.Open(mysql.Open(mysqlContainer.GetConnection(ctx)), &gorm.Config{
+	db := newTestMysql(t, &gorm.Config{
 		QueryFields: true,
 	})
-	if err != nil {
-		t.Fatalf("mounting db: %v", err)
-	}
 
 	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
 
 	if err := r.Create(ctx, &interfaces.User{
 		Name: "John Doe",
@@ -276,20 +225,14 @@ func TestUserMysqlRepoCreate(t *testing.T) {
 }
 
 func TestUserMysqlRepoDelete(t *testing.T) {
-	ctx := context.Background()
+	t.Parallel()
 
-	mysqlContainer, close, err := NewTestContainerMysql(ctx)
-	if err != nil {
-		t.Fatalf("mounting db container: %v", err)
-	}
-	defer close(ctx)
+	ctx := context.Background()
 
-	db, err := gorm.Open(mysql.Open(mysqlContainer.GetConnection(ctx)), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("mounting db: %v", err)
-	}
+	db := newTestMysql(t, &gorm.Config{})
 
 	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
 
 	t.Run("single", func(t *testing.T) {
 		u := &interfaces.User{
@@ -361,22 +304,16 @@ func TestUserMysqlRepoDelete(t *testing.T) {
 }
 
 func TestUserMysqlRepoUpdate(t *testing.T) {
-	ctx := context.Background()
+	t.Parallel()
 
-	mysqlContainer, close, err := NewTestContainerMysql(ctx)
-	if err != nil {
-		t.Fatalf("mounting db container: %v", err)
-	}
-	defer close(ctx)
+	ctx := context.Background()
 
-	db, err := gorm.Open(mysql.Open(mysqlContainer.GetConnection(ctx)), &gorm.Config{
+	db := newTestMysql(t, &gorm.Config{
 		QueryFields: true,
 	})
-	if err != nil {
-		t.Fatalf("mounting db: %v", err)
-	}
 
 	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
 
 	u := interfaces.User{
 		Name: "John Doe",
@@ -409,3 +346,167 @@ func TestUserMysqlRepoUpdate(t *testing.T) {
 
 	assert.Equal(t, "new name", userUpdated.Name, "they should be equal")
 }
+
+func TestUserMysqlRepoStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	t.Run("per day", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{}, interfaces.StatsQuery{})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, int64(6), stats.Total, "they should be equal")
+		assert.Equal(t, float64(43), stats.AverageAge, "they should be equal")
+		assert.Equal(t, 7, len(stats.AgeBuckets), "they should be equal")
+		assert.Equal(t, int64(0), stats.AgeBuckets[0].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[1].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[6].Count, "they should be equal")
+		assert.Equal(t, 6, len(stats.SignUps), "they should be equal")
+	})
+
+	t.Run("per week", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{}, interfaces.StatsQuery{Interval: interfaces.IntervalWeek})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, 2, len(stats.SignUps), "they should be equal")
+		assert.Equal(t, int64(5), stats.SignUps[0].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.SignUps[1].Count, "they should be equal")
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{AgeGte: 22, AgeLte: 45}, interfaces.StatsQuery{
+			AgeBoundaries: []uint8{35},
+		})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, int64(4), stats.Total, "they should be equal")
+		assert.Equal(t, int64(2), stats.AgeBuckets[0].Count, "they should be equal")
+		assert.Equal(t, int64(2), stats.AgeBuckets[1].Count, "they should be equal")
+	})
+
+	t.Run("utc days", func(t *testing.T) {
+		late := time.Date(2024, 4, 10, 1, 0, 0, 0, time.FixedZone("+05:00", 5*60*60))
+		if err := r.Create(ctx, &interfaces.User{ID: 100, Name: "late", Age: 30, CreatedAt: late, UpdatedAt: late}); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+
+		stats, err := r.Stats(ctx, interfaces.Filters{IDs: []int64{100}}, interfaces.StatsQuery{})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		if len(stats.SignUps) != 1 {
+			t.Fatalf("expected 1 period, got %d", len(stats.SignUps))
+		}
+
+		assert.Equal(t, "2024-04-09", stats.SignUps[0].Period.Format("2006-01-02"), "they should be equal")
+	})
+}
+
+func TestUserMysqlRepoFields(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+	fields := utils.WithFields(interfaces.FieldID, interfaces.FieldName)
+
+	t.Run("get by id", func(t *testing.T) {
+		user, err := r.GetById(ctx, 1, fields)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Equal(t, "first", user.Name, "they should be equal")
+		assert.Equal(t, uint8(0), user.Age, "they should be equal")
+	})
+
+	t.Run("get all", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{}, fields)
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Equal(t, uint(6), users[0].ID, "they should be equal")
+		assert.True(t, users[0].CreatedAt.IsZero(), "they should be equal")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := r.GetById(ctx, 1, utils.WithFields("password"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestUserMysqlRepoGetByIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	users, missing, err := r.GetByIDs(ctx, []int64{5, 42, 2}, utils.WithFields(interfaces.FieldName))
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	assert.Equal(t, 2, len(users), "they should be equal")
+	assert.Equal(t, uint(5), users[0].ID, "they should be equal")
+	assert.Equal(t, "second", users[1].Name, "they should be equal")
+	assert.Equal(t, []int64{42}, missing, "they should be equal")
+}
+
+func TestUserMysqlRepoExplainGetAll(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	plan, err := r.(interfaces.Explainer).ExplainGetAll(ctx, interfaces.Filters{AgeGte: 22})
+	if err != nil {
+		t.Fatalf("explaining users: %v", err)
+	}
+
+	assert.Contains(t, plan, "query_block", "they should be equal")
+}
+
+func TestUserMysqlRepoMaxID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	id, err := r.(interfaces.MaxIDer).MaxID(ctx)
+	if err != nil {
+		t.Fatalf("getting max id: %v", err)
+	}
+
+	assert.Equal(t, int64(6), id, "they should be equal")
+}
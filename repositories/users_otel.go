@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "repos/repositories"
+
+type otelConfig struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+type OtelOption func(*otelConfig)
+
+func defaultOtelConfig() otelConfig {
+	return otelConfig{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+}
+
+func WithTracerProvider(provider trace.TracerProvider) OtelOption {
+	return func(c *otelConfig) {
+		c.tracerProvider = provider
+	}
+}
+
+func WithMeterProvider(provider metric.MeterProvider) OtelOption {
+	return func(c *otelConfig) {
+		c.meterProvider = provider
+	}
+}
+
+// userRepoOtel reports a span, the latency and the errors of every call to
+// the wrapped repository. Wire utils.WithTracing or utils.MongoMonitor in the
+// backend for its queries to show up under these spans.
+type userRepoOtel struct {
+	interfaces.UsersRepo
+	backend  string
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+func NewUserRepoOtel(repo interfaces.UsersRepo, backend string, opts ...OtelOption) interfaces.UsersRepo {
+	config := defaultOtelConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	meter := config.meterProvider.Meter(instrumentationName)
+
+	duration, err := meter.Float64Histogram(
+		"users_repo.duration",
+		metric.WithDescription("Duration of the users repository calls."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	errors, err := meter.Int64Counter(
+		"users_repo.errors",
+		metric.WithDescription("Number of users repository calls that failed."),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return &userRepoOtel{
+		UsersRepo: repo,
+		backend:   backend,
+		tracer:    config.tracerProvider.Tracer(instrumentationName),
+		duration:  duration,
+		errors:    errors,
+	}
+}
+
+// start opens the span of method, the returned func ends it with the error
+// and result attributes of the call.
+func (r userRepoOtel) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, func(error, ...attribute.KeyValue)) {
+	common := []attribute.KeyValue{
+		attribute.String("db.system", r.backend),
+		attribute.String("users.method", method),
+	}
+
+	ctx, span := r.tracer.Start(ctx, "UsersRepo."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(append(common, attrs...)...),
+	)
+	start := time.Now()
+
+	return ctx, func(err error, attrs ...attribute.KeyValue) {
+		span.SetAttributes(attrs...)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			r.errors.Add(ctx, 1, metric.WithAttributes(common...))
+		}
+
+		r.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+			append(common, attribute.Bool("error", err != nil))...,
+		))
+
+		span.End()
+	}
+}
+
+func filterAttributes(filters interfaces.Filters) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.Int("users.filter.offset", filters.Offset),
+		attribute.Int("users.filter.limit", filters.Limit),
+		attribute.String("users.filter.order_by", string(filters.OrderBy)),
+		attribute.Int("users.filter.ids", len(filters.IDs)),
+	}
+
+	if filters.AgeGte != 0 {
+		attrs = append(attrs, attribute.Int("users.filter.age_gte", int(filters.AgeGte)))
+	}
+
+	if filters.AgeLte != 0 {
+		attrs = append(attrs, attribute.Int("users.filter.age_lte", int(filters.AgeLte)))
+	}
+
+	if !filters.CreatedAtGte.IsZero() {
+		attrs = append(attrs, attribute.String("users.filter.created_at_gte", filters.CreatedAtGte.Format(time.RFC3339)))
+	}
+
+	if !filters.CreatedAtLte.IsZero() {
+		attrs = append(attrs, attribute.String("users.filter.created_at_lte", filters.CreatedAtLte.Format(time.RFC3339)))
+	}
+
+	return attrs
+}
+
+func (r userRepoOtel) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	ctx, end := r.start(ctx, MethodGetById, attribute.Int64("users.id", id))
+
+	user, err := r.UsersRepo.GetById(ctx, id, opts...)
+	end(err, attribute.Bool("users.found", user != nil))
+
+	return user, err
+}
+
+func (r userRepoOtel) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	ctx, end := r.start(ctx, MethodGetAll, filterAttributes(filters)...)
+
+	users, total, err := r.UsersRepo.GetAll(ctx, filters, opts...)
+	end(err, attribute.Int("users.rows", len(users)), attribute.Int64("users.total", total))
+
+	return users, total, err
+}
+
+func (r userRepoOtel) GetByIDs(ctx context.Context, ids []int64, opts ...utils.Options) ([]*interfaces.User, []int64, error) {
+	ctx, end := r.start(ctx, MethodGetByIDs, attribute.Int("users.ids", len(ids)))
+
+	users, missing, err := r.UsersRepo.GetByIDs(ctx, ids, opts...)
+	end(err, attribute.Int("users.rows", len(users)), attribute.Int("users.missing", len(missing)))
+
+	return users, missing, err
+}
+
+func (r userRepoOtel) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	ctx, end := r.start(ctx, MethodCreate)
+
+	err := r.UsersRepo.Create(ctx, user, opts...)
+	end(err, attribute.Int64("users.id", int64(user.ID)))
+
+	return err
+}
+
+func (r userRepoOtel) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	fields := make([]string, 0, len(vals))
+	for k := range vals {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	ctx, end := r.start(ctx, MethodUpdate,
+		attribute.Int64("users.id", int64(user.ID)),
+		attribute.StringSlice("users.fields", fields),
+	)
+
+	err := r.UsersRepo.Update(ctx, user, vals, opts...)
+	end(err)
+
+	return err
+}
+
+func (r userRepoOtel) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	ctx, end := r.start(ctx, MethodDelete, attribute.Int("users.ids", len(ids)))
+
+	err := r.UsersRepo.Delete(ctx, ids, opts...)
+	end(err)
+
+	return err
+}
+
+func (r userRepoOtel) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	ctx, end := r.start(ctx, MethodStats, append(filterAttributes(filters), attribute.String("users.interval", string(query.Interval)))...)
+
+	stats, err := r.UsersRepo.Stats(ctx, filters, query, opts...)
+
+	var total int64
+	if stats != nil {
+		total = stats.Total
+	}
+	end(err, attribute.Int64("users.total", total))
+
+	return stats, err
+}
@@ -0,0 +1,88 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestUserRepoOtel(t *testing.T) {
+	ctx := context.Background()
+
+	spans := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	fake := mocks.NewRecordingUsersRepo(fixtures.NewUser(fixtures.WithID(1), fixtures.WithAge(30)), fixtures.NewUser(fixtures.WithID(2), fixtures.WithAge(40)))
+	r := repositories.NewUserRepoOtel(fake, "mysql",
+		repositories.WithTracerProvider(tracerProvider),
+		repositories.WithMeterProvider(meterProvider),
+	)
+
+	if _, _, err := r.GetAll(ctx, interfaces.Filters{Limit: 10, AgeGte: 20}); err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	refused := errors.New("connection refused")
+	fake.Fail(refused)
+	_, err := r.GetById(ctx, 1)
+	assert.Equal(t, refused, err, "they should be equal")
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(ended))
+	}
+
+	getAll := attributes(ended[0].Attributes())
+	assert.Equal(t, "UsersRepo.GetAll", ended[0].Name(), "they should be equal")
+	assert.Equal(t, "mysql", getAll["db.system"].AsString(), "they should be equal")
+	assert.Equal(t, int64(10), getAll["users.filter.limit"].AsInt64(), "they should be equal")
+	assert.Equal(t, int64(20), getAll["users.filter.age_gte"].AsInt64(), "they should be equal")
+	assert.Equal(t, int64(2), getAll["users.rows"].AsInt64(), "they should be equal")
+	assert.Equal(t, codes.Unset, ended[0].Status().Code, "they should be equal")
+
+	assert.Equal(t, "UsersRepo.GetById", ended[1].Name(), "they should be equal")
+	assert.Equal(t, codes.Error, ended[1].Status().Code, "they should be equal")
+	assert.Equal(t, 1, len(ended[1].Events()), "they should be equal")
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("collecting metrics: %v", err)
+	}
+
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	duration := metrics["users_repo.duration"].Data.(metricdata.Histogram[float64])
+	assert.Equal(t, 2, len(duration.DataPoints), "they should be equal")
+
+	errs := metrics["users_repo.errors"].Data.(metricdata.Sum[int64])
+	assert.Equal(t, 1, len(errs.DataPoints), "they should be equal")
+	assert.Equal(t, int64(1), errs.DataPoints[0].Value, "they should be equal")
+}
+
+func attributes(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, a := range attrs {
+		m[a.Key] = a.Value
+	}
+
+	return m
+}
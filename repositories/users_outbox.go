@@ -0,0 +1,317 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+// OutboxMessage is a user event waiting in the outbox to be published. The
+// sequence orders the messages of a user as their changes were committed,
+// the consumers use it to discard the messages published twice.
+type OutboxMessage struct {
+	Sequence    int64                    `gorm:"primaryKey;autoIncrement" bson:"_id"`
+	Type        interfaces.UserEventType `gorm:"size:16;not null"`
+	UserID      int64                    `gorm:"not null"`
+	Payload     map[string]interface{}   `gorm:"type:text;serializer:json"`
+	OccurredAt  time.Time                `gorm:"not null"`
+	PublishedAt *time.Time               `gorm:"index"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+func (m OutboxMessage) Event() interfaces.UserEvent {
+	return interfaces.UserEvent{
+		Type:       m.Type,
+		UserID:     m.UserID,
+		Payload:    m.Payload,
+		OccurredAt: m.OccurredAt,
+	}
+}
+
+// OutboxStore keeps the messages of the outbox, written in the transaction
+// of the changes they describe and read by the Relay.
+type OutboxStore interface {
+	Transactor
+	// Write inserts the messages in the transaction of ctx and opts, giving
+	// them their sequence.
+	Write(ctx context.Context, opts []utils.Options, messages ...OutboxMessage) error
+	// Pending returns up to limit messages not published yet, by sequence,
+	// leaving out those of the excluded users.
+	Pending(ctx context.Context, limit int, exclude ...int64) ([]OutboxMessage, error)
+	// MarkPublished records the messages as published at.
+	MarkPublished(ctx context.Context, sequences []int64, at time.Time) error
+	// Purge deletes the messages published before, and returns how many.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type outboxStoreGorm struct {
+	gormTransactor
+}
+
+// NewOutboxStoreMysql returns the store of the outbox table, in the database
+// of the gorm repositories, MySQL, PostgreSQL or SQLite. The table is
+// created by MigrateOutbox. The sequences are the auto increment ids, the
+// changes of a user lock its row so its messages get them in commit order.
+func NewOutboxStoreMysql(db *gorm.DB) OutboxStore {
+	return outboxStoreGorm{gormTransactor{db: db}}
+}
+
+// MigrateOutbox creates or updates the outbox table.
+func MigrateOutbox(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).AutoMigrate(&OutboxMessage{})
+}
+
+func (s outboxStoreGorm) Write(ctx context.Context, opts []utils.Options, messages ...OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return s.statement(ctx, opts).Create(&messages).Error
+}
+
+func (s outboxStoreGorm) Pending(ctx context.Context, limit int, exclude ...int64) ([]OutboxMessage, error) {
+	stmt := s.db.WithContext(ctx).Where("published_at IS NULL")
+	if len(exclude) > 0 {
+		stmt = stmt.Where("user_id NOT IN ?", exclude)
+	}
+
+	var messages []OutboxMessage
+	err := stmt.
+		Order("sequence").
+		Limit(limit).
+		Find(&messages).
+		Error
+
+	return messages, err
+}
+
+func (s outboxStoreGorm) MarkPublished(ctx context.Context, sequences []int64, at time.Time) error {
+	if len(sequences) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).
+		Model(&OutboxMessage{}).
+		Where("sequence IN ?", sequences).
+		Update("published_at", at).
+		Error
+}
+
+func (s outboxStoreGorm) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("published_at < ?", before).
+		Delete(&OutboxMessage{})
+
+	return result.RowsAffected, result.Error
+}
+
+type outboxStoreMongo struct {
+	mongoTransactor
+	collection *mongo.Collection
+	counters   *mongo.Collection
+}
+
+// NewOutboxStoreMongo returns the store of the outbox collection of db,
+// which must belong to a replica set for the transactions. The sequences
+// are taken from a counter of the outbox_sequence collection incremented in
+// the transaction, the concurrent changes conflict on it and are committed
+// one after the other. The indexes are created by MigrateOutboxMongo.
+func NewOutboxStoreMongo(db *mongo.Database) OutboxStore {
+	return outboxStoreMongo{
+		mongoTransactor: mongoTransactor{client: db.Client()},
+		collection:      db.Collection("outbox"),
+		counters:        db.Collection("outbox_sequence"),
+	}
+}
+
+// MigrateOutboxMongo creates the index of the outbox collection reading the
+// pending messages by sequence and purging the published ones.
+func MigrateOutboxMongo(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("outbox").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{"publishedat", 1}, {"_id", 1}},
+	})
+
+	return err
+}
+
+func (s outboxStoreMongo) Write(ctx context.Context, opts []utils.Options, messages ...OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var counter struct {
+		Sequence int64 `bson:"sequence"`
+	}
+	err := s.counters.FindOneAndUpdate(ctx,
+		bson.D{{"_id", "outbox"}},
+		bson.D{{"$inc", bson.D{{"sequence", int64(len(messages))}}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return err
+	}
+
+	docs := make([]interface{}, len(messages))
+	for i, m := range messages {
+		m.Sequence = counter.Sequence - int64(len(messages)-1-i)
+		docs[i] = m
+	}
+
+	_, err = s.collection.InsertMany(ctx, docs)
+
+	return err
+}
+
+func (s outboxStoreMongo) Pending(ctx context.Context, limit int, exclude ...int64) ([]OutboxMessage, error) {
+	filter := bson.D{{"publishedat", nil}}
+	if len(exclude) > 0 {
+		filter = append(filter, bson.E{Key: "userid", Value: bson.D{{"$nin", exclude}}})
+	}
+
+	cursor, err := s.collection.Find(ctx,
+		filter,
+		options.Find().SetSort(bson.D{{"_id", 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []OutboxMessage
+	err = cursor.All(ctx, &messages)
+
+	return messages, err
+}
+
+func (s outboxStoreMongo) MarkPublished(ctx context.Context, sequences []int64, at time.Time) error {
+	if len(sequences) == 0 {
+		return nil
+	}
+
+	_, err := s.collection.UpdateMany(ctx,
+		bson.D{{"_id", bson.D{{"$in", sequences}}}},
+		bson.D{{"$set", bson.D{{"publishedat", at}}}},
+	)
+
+	return err
+}
+
+func (s outboxStoreMongo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.collection.DeleteMany(ctx, bson.D{{"publishedat", bson.D{{"$lt", before}}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+type outboxConfig struct {
+	now func() time.Time
+}
+
+type OutboxOption func(*outboxConfig)
+
+func defaultOutboxConfig() outboxConfig {
+	return outboxConfig{now: time.Now}
+}
+
+// WithOutboxClock replaces the clock dating the events.
+func WithOutboxClock(now func() time.Time) OutboxOption {
+	return func(c *outboxConfig) {
+		c.now = now
+	}
+}
+
+// userRepoOutbox writes an event to the outbox for every Create, Update and
+// Delete, in the same transaction as the change, so every committed change
+// is eventually published and nothing else is. The updates and deletes of
+// missing users change nothing and write no event.
+type userRepoOutbox struct {
+	interfaces.UsersRepo
+	store  OutboxStore
+	config outboxConfig
+}
+
+// NewUserRepoOutbox writes the events of the changes made through repo to
+// the outbox, the store must use the same database.
+func NewUserRepoOutbox(repo interfaces.UsersRepo, store OutboxStore, opts ...OutboxOption) interfaces.UsersRepo {
+	config := defaultOutboxConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	return &userRepoOutbox{UsersRepo: repo, store: store, config: config}
+}
+
+func (r userRepoOutbox) message(event interfaces.UserEventType, id int64, payload map[string]interface{}) OutboxMessage {
+	return OutboxMessage{
+		Type:       event,
+		UserID:     id,
+		Payload:    payload,
+		OccurredAt: r.config.now(),
+	}
+}
+
+func (r userRepoOutbox) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	return r.store.Transaction(ctx, opts, func(ctx context.Context, opts []utils.Options) error {
+		if err := r.UsersRepo.Create(ctx, user, opts...); err != nil {
+			return err
+		}
+
+		return r.store.Write(ctx, opts, r.message(interfaces.UserCreated, int64(user.ID), userValues(user)))
+	})
+}
+
+func (r userRepoOutbox) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	return r.store.Transaction(ctx, opts, func(ctx context.Context, opts []utils.Options) error {
+		current, err := r.UsersRepo.GetById(ctx, int64(user.ID), lockedRead(opts)...)
+		if err != nil {
+			return err
+		}
+
+		if err := r.UsersRepo.Update(ctx, user, vals, opts...); err != nil {
+			return err
+		}
+
+		// The gorm repositories return an empty user when it is missing.
+		if current == nil || current.ID == 0 {
+			return nil
+		}
+
+		payload := map[string]interface{}{}
+		for field, v := range vals {
+			payload[field] = v
+		}
+
+		return r.store.Write(ctx, opts, r.message(interfaces.UserUpdated, int64(user.ID), payload))
+	})
+}
+
+func (r userRepoOutbox) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	return r.store.Transaction(ctx, opts, func(ctx context.Context, opts []utils.Options) error {
+		users, _, err := r.UsersRepo.GetByIDs(ctx, ids, lockedRead(opts)...)
+		if err != nil {
+			return err
+		}
+
+		if err := r.UsersRepo.Delete(ctx, ids, opts...); err != nil {
+			return err
+		}
+
+		messages := make([]OutboxMessage, len(users))
+		for i, u := range users {
+			messages[i] = r.message(interfaces.UserDeleted, int64(u.ID), map[string]interface{}{interfaces.FieldID: u.ID})
+		}
+
+		return r.store.Write(ctx, opts, messages...)
+	})
+}
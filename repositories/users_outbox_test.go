@@ -0,0 +1,278 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var outboxNow = time.Date(2024, 4, 16, 10, 0, 0, 0, time.UTC)
+
+// recordingPublisher keeps the messages it published and fails those of
+// the users in fail.
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []repositories.OutboxMessage
+	fail      map[int64]bool
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, m repositories.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.fail[m.UserID] {
+		return errors.New("broker unavailable")
+	}
+
+	p.published = append(p.published, m)
+
+	return nil
+}
+
+func (p *recordingPublisher) events() []interfaces.UserEventType {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := make([]interfaces.UserEventType, len(p.published))
+	for i, m := range p.published {
+		events[i] = m.Type
+	}
+
+	return events
+}
+
+// testUserRepoOutbox checks the messages written for the changes of r, a
+// repository loaded with the canonical fixtures, and their relay.
+func testUserRepoOutbox(t *testing.T, r interfaces.UsersRepo, store repositories.OutboxStore) {
+	ctx := context.Background()
+	outbox := repositories.NewUserRepoOutbox(r, store, repositories.WithOutboxClock(func() time.Time { return outboxNow }))
+
+	t.Run("changes", func(t *testing.T) {
+		if err := outbox.Create(ctx, &interfaces.User{ID: 100, Name: "john", Age: 30}); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+
+		if err := outbox.Update(ctx, &interfaces.User{ID: 100}, map[string]interface{}{interfaces.FieldName: "renamed"}); err != nil {
+			t.Fatalf("updating user: %v", err)
+		}
+
+		if err := outbox.Update(ctx, &interfaces.User{ID: 42}, map[string]interface{}{interfaces.FieldName: "nobody"}); err != nil {
+			t.Fatalf("updating user: %v", err)
+		}
+
+		if err := outbox.Delete(ctx, []int64{100, 42}); err != nil {
+			t.Fatalf("deleting user: %v", err)
+		}
+
+		messages, err := store.Pending(ctx, 10)
+		if err != nil {
+			t.Fatalf("reading outbox: %v", err)
+		}
+
+		if len(messages) != 3 {
+			t.Fatalf("expected 3 messages, got %d", len(messages))
+		}
+
+		assert.Equal(t, []interfaces.UserEventType{interfaces.UserCreated, interfaces.UserUpdated, interfaces.UserDeleted}, []interfaces.UserEventType{messages[0].Type, messages[1].Type, messages[2].Type}, "they should be equal")
+		assert.Less(t, messages[0].Sequence, messages[1].Sequence, "should be ordered")
+		assert.Less(t, messages[1].Sequence, messages[2].Sequence, "should be ordered")
+		assert.EqualValues(t, "john", messages[0].Payload[interfaces.FieldName], "they should be equal")
+		assert.Equal(t, map[string]interface{}{interfaces.FieldName: "renamed"}, messages[1].Payload, "they should be equal")
+		assert.Equal(t, int64(100), messages[2].UserID, "they should be equal")
+		assert.True(t, outboxNow.Equal(messages[0].OccurredAt), "they should be equal")
+	})
+
+	t.Run("relay", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		relay := repositories.NewRelay(store, publisher)
+
+		published, err := relay.Poll(ctx)
+		if err != nil {
+			t.Fatalf("relaying outbox: %v", err)
+		}
+
+		assert.Equal(t, 3, published, "they should be equal")
+		assert.Equal(t, []interfaces.UserEventType{interfaces.UserCreated, interfaces.UserUpdated, interfaces.UserDeleted}, publisher.events(), "they should be equal")
+
+		published, err = relay.Poll(ctx)
+		if err != nil {
+			t.Fatalf("relaying outbox: %v", err)
+		}
+
+		assert.Equal(t, 0, published, "they should be equal")
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		// a retention in the future purges every published message.
+		relay := repositories.NewRelay(store, &recordingPublisher{}, repositories.WithRelayRetention(-time.Minute))
+
+		purged, err := relay.Purge(ctx)
+		if err != nil {
+			t.Fatalf("purging outbox: %v", err)
+		}
+
+		assert.Equal(t, int64(3), purged, "they should be equal")
+	})
+}
+
+func TestUserSQLiteRepoOutbox(t *testing.T) {
+	ctx := context.Background()
+	db := NewTestSQLite(ctx, t)
+
+	if err := repositories.MigrateOutbox(ctx, db); err != nil {
+		t.Fatalf("creating outbox table: %v", err)
+	}
+
+	r := repositories.NewUserRepoSQLite(db)
+	store := repositories.NewOutboxStoreMysql(db)
+
+	testUserRepoOutbox(t, r, store)
+
+	t.Run("rolled back with the change", func(t *testing.T) {
+		outbox := repositories.NewUserRepoOutbox(r, store)
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := outbox.Create(ctx, &interfaces.User{ID: 101, Name: "rolled back"}, utils.WithTx(tx)); err != nil {
+				return err
+			}
+
+			return errors.New("rollback")
+		})
+		assert.Error(t, err, "should fail")
+
+		messages, err := store.Pending(ctx, 10)
+		if err != nil {
+			t.Fatalf("reading outbox: %v", err)
+		}
+
+		assert.Empty(t, messages, "should be empty")
+	})
+
+	t.Run("failed user waits for the next poll", func(t *testing.T) {
+		outbox := repositories.NewUserRepoOutbox(r, store)
+
+		for _, u := range []interfaces.User{{ID: 102, Name: "a"}, {ID: 103, Name: "b"}} {
+			u := u
+			if err := outbox.Create(ctx, &u); err != nil {
+				t.Fatalf("creating user: %v", err)
+			}
+		}
+
+		if err := outbox.Update(ctx, &interfaces.User{ID: 102}, map[string]interface{}{interfaces.FieldAge: 40}); err != nil {
+			t.Fatalf("updating user: %v", err)
+		}
+
+		publisher := &recordingPublisher{fail: map[int64]bool{102: true}}
+		relay := repositories.NewRelay(store, publisher)
+
+		published, err := relay.Poll(ctx)
+		assert.Error(t, err, "should fail")
+		assert.Equal(t, 1, published, "they should be equal")
+		assert.Equal(t, int64(103), publisher.published[0].UserID, "they should be equal")
+
+		publisher.fail = nil
+
+		published, err = relay.Poll(ctx)
+		if err != nil {
+			t.Fatalf("relaying outbox: %v", err)
+		}
+
+		assert.Equal(t, 2, published, "they should be equal")
+		assert.Equal(t, []interfaces.UserEventType{interfaces.UserCreated, interfaces.UserCreated, interfaces.UserUpdated}, publisher.events(), "they should be equal")
+	})
+
+	t.Run("failed user does not hold back the others", func(t *testing.T) {
+		outbox := repositories.NewUserRepoOutbox(r, store)
+
+		for _, name := range []string{"a", "b"} {
+			if err := outbox.Update(ctx, &interfaces.User{ID: 102}, map[string]interface{}{interfaces.FieldName: name}); err != nil {
+				t.Fatalf("updating user: %v", err)
+			}
+		}
+
+		if err := outbox.Update(ctx, &interfaces.User{ID: 103}, map[string]interface{}{interfaces.FieldAge: 40}); err != nil {
+			t.Fatalf("updating user: %v", err)
+		}
+
+		publisher := &recordingPublisher{fail: map[int64]bool{102: true}}
+		relay := repositories.NewRelay(store, publisher, repositories.WithRelayBatch(2))
+
+		published, err := relay.Poll(ctx)
+		assert.Error(t, err, "should fail")
+		assert.Equal(t, 1, published, "they should be equal")
+		assert.Equal(t, int64(103), publisher.published[0].UserID, "they should be equal")
+
+		publisher.fail = nil
+
+		published, err = relay.Poll(ctx)
+		if err != nil {
+			t.Fatalf("relaying outbox: %v", err)
+		}
+
+		assert.Equal(t, 2, published, "they should be equal")
+	})
+
+	t.Run("run", func(t *testing.T) {
+		outbox := repositories.NewUserRepoOutbox(r, store)
+		if err := outbox.Delete(ctx, []int64{1, 2}); err != nil {
+			t.Fatalf("deleting users: %v", err)
+		}
+
+		publisher := &recordingPublisher{}
+		relay := repositories.NewRelay(store, publisher, repositories.WithRelayInterval(time.Millisecond))
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			relay.Run(ctx)
+			close(done)
+		}()
+
+		assert.Eventually(t, func() bool { return len(publisher.events()) == 2 }, time.Second, time.Millisecond, "should publish the deletes")
+
+		cancel()
+		<-done
+	})
+}
+
+func TestUserMysqlRepoOutbox(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	if err := repositories.MigrateOutbox(ctx, db); err != nil {
+		t.Fatalf("creating outbox table: %v", err)
+	}
+
+	r := repositories.NewUserRepoMysql(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	testUserRepoOutbox(t, r, repositories.NewOutboxStoreMysql(db))
+}
+
+func TestUserMongoRepoOutbox(t *testing.T) {
+	t.Parallel()
+
+	db := newTestMongo(t)
+
+	if err := repositories.MigrateOutboxMongo(context.Background(), db); err != nil {
+		t.Fatalf("creating outbox indexes: %v", err)
+	}
+
+	r := repositories.NewUserRepoMongo(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	testUserRepoOutbox(t, r, repositories.NewOutboxStoreMongo(db))
+}
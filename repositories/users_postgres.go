@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	postgresSerializationFailure = "40001"
+	postgresDeadlock             = "40P01"
+)
+
+// userRepoPostgres is the generic gorm repository instantiated for the
+// users, only the statements using functions of the dialect differ from
+// userRepoMysql.
+type userRepoPostgres struct {
+	gormRepo[interfaces.User, int64]
+}
+
+func NewUserRepoPostgres(db *gorm.DB) interfaces.UsersRepo {
+	return &userRepoPostgres{gormRepo[interfaces.User, int64]{db: db, dialect: postgresDialect, entity: interfaces.UserEntity}}
+}
+
+func (r userRepoPostgres) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	query, err := filters.Query()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.gormRepo.GetAll(ctx, query, opts...)
+}
+
+// ExplainGetAll returns the EXPLAIN (FORMAT JSON) of the query run by GetAll.
+func (r userRepoPostgres) ExplainGetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) (string, error) {
+	q, err := compileFilters(r.dialect, filters)
+	if err != nil {
+		return "", err
+	}
+
+	var users []*interfaces.User
+	stmt := q.Query(ctx, r.db, opts...).
+		Scopes(q.Page).
+		Session(&gorm.Session{DryRun: true}).
+		Find(&users).
+		Statement
+
+	var plan string
+	err = r.db.
+		WithContext(ctx).
+		Raw("EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).
+		Row().
+		Scan(&plan)
+
+	return plan, err
+}
+
+func (r userRepoPostgres) MaxID(ctx context.Context) (int64, error) {
+	var id *int64
+	err := r.db.WithContext(ctx).Model(&interfaces.User{}).Select("MAX(id)").Scan(&id).Error
+	if err != nil || id == nil {
+		return 0, err
+	}
+
+	return *id, nil
+}
+
+func (r userRepoPostgres) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	q, err := compileFilters(r.dialect, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err = query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &interfaces.UserStats{AgeBuckets: query.AgeBuckets()}
+
+	var totals struct {
+		Total      int64
+		AverageAge float64
+	}
+
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select("COUNT(*) AS total, CAST(COALESCE(AVG(age), 0) AS DOUBLE PRECISION) AS average_age").
+		Scan(&totals).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Total, stats.AverageAge = totals.Total, totals.AverageAge
+
+	bucket, args := ageBucketCase(query.AgeBoundaries)
+
+	var ages []struct {
+		Bucket int
+		Count  int64
+	}
+
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select("("+bucket+") AS bucket, COUNT(*) AS count", args...).
+		Group("bucket").
+		Scan(&ages).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range ages {
+		stats.AgeBuckets[a.Bucket].Count = a.Count
+	}
+
+	// The periods are UTC days, as in MySQL and Mongo, whatever the time
+	// zone of the session.
+	day := "(created_at AT TIME ZONE 'UTC')"
+	period := "CAST(" + day + " AS DATE)"
+	if query.Interval == interfaces.IntervalWeek {
+		period = "CAST(DATE_TRUNC('week', " + day + ") AS DATE)"
+	}
+
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select(period + " AS period, COUNT(*) AS count").
+		Group("period").
+		Order("period").
+		Scan(&stats.SignUps).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// IsTransientPostgres reports whether err is worth retrying: deadlocks and
+// serialization failures.
+func IsTransientPostgres(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == postgresDeadlock || pgErr.Code == postgresSerializationFailure
+	}
+
+	return false
+}
@@ -0,0 +1,538 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setupPostgres loads the canonical users and moves the id sequence past
+// them, the ids they are inserted with do not advance it.
+func setupPostgres(t *testing.T, db *gorm.DB, r interfaces.UsersRepo) {
+	t.Helper()
+
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	if err := db.Exec("SELECT setval('users_id_seq', (SELECT MAX(id) FROM users))").Error; err != nil {
+		t.Fatalf("moving id sequence: %v", err)
+	}
+}
+
+func TestUserPostgresRepoGetByID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{
+		QueryFields: true,
+	})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	user, err := r.GetById(ctx, 1)
+	if err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	assert.Equal(t, "first", user.Name, "they should be equal")
+}
+
+func TestUserPostgresRepoGetAll(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	t.Run("check limit to 2", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{Offset: 0, Limit: 2})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, 2, len(users), "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("check limit to 6", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{Offset: 0, Limit: 6})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, 6, len(users), "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("offset beging", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{Offset: 0, Limit: 6})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, uint(6), users[0].ID, "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("offset move", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{Offset: 1, Limit: 6})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, uint(5), users[0].ID, "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("lte test", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{
+			CreatedAtLte: time.Date(2024, 4, 13, 0, 0, 0, 0, time.Local),
+		})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, uint(3), users[0].ID, "they should be equal")
+		assert.Equal(t, int64(3), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("lte default", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, uint(6), users[0].ID, "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("gte test", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{
+			CreatedAtGte: time.Date(2024, 4, 14, 0, 0, 0, 0, time.Local),
+		})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, 2, len(users), "they should be equal")
+		assert.Equal(t, int64(2), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("between gte and lte", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{
+			CreatedAtGte: time.Date(2024, 4, 13, 0, 0, 0, 0, time.Local),
+			CreatedAtLte: time.Date(2024, 4, 15, 0, 0, 0, 0, time.Local),
+		})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, 2, len(users), "they should be equal")
+		assert.Equal(t, int64(2), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("order by age", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{
+			OrderBy: interfaces.OrderByAge,
+		})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, uint8(66), users[0].Age, "they should be equal")
+		assert.Equal(t, uint8(55), users[1].Age, "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("order by name", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{
+			OrderBy: interfaces.OrderByName,
+		})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, "third", users[0].Name, "they should be equal")
+		assert.Equal(t, "six", users[1].Name, "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("age range", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{
+			AgeGte: 22,
+			AgeLte: 45,
+		})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, 4, len(users), "they should be equal")
+		assert.Equal(t, int64(4), total, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+
+	t.Run("by ids", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{
+			IDs: []int64{3, 4},
+		})
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Equal(t, 2, len(users), "they should be equal")
+		assert.Equal(t, int64(2), total, "they should be equal")
+		assert.Equal(t, uint(3), users[0].ID, "they should be equal")
+		assert.Equal(t, uint(4), users[1].ID, "they should be equal")
+		assert.Nil(t, err, "they should be equal")
+	})
+}
+
+func TestUserPostgresRepoCreate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{
+		QueryFields: true,
+	})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	if err := r.Create(ctx, &interfaces.User{
+		Name: "John Doe",
+		Age:  5,
+	}); err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	users, _, err := r.GetAll(ctx, interfaces.Filters{Offset: 0, Limit: 10})
+	if err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	assert.Equal(t, uint(6), users[0].ID, "they should be equal")
+}
+
+func TestUserPostgresRepoDelete(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	t.Run("single", func(t *testing.T) {
+		u := &interfaces.User{
+			Name: "John Doe",
+		}
+
+		if err := r.Create(ctx, u); err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		user, err := r.GetById(ctx, int64(u.ID))
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Equal(t, uint(7), user.ID, "they should be equal")
+
+		if err := r.Delete(ctx, []int64{int64(u.ID)}); err != nil {
+			t.Fatalf("deleting user table: %v", err)
+		}
+
+		userDelete, err := r.GetById(ctx, int64(u.ID))
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Empty(t, userDelete, "they should be equal")
+		assert.Nil(t, err)
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		u1 := &interfaces.User{
+			Name: "John Doe",
+		}
+
+		if err := r.Create(ctx, u1); err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		u2 := &interfaces.User{
+			Name: "John Doe",
+		}
+
+		if err := r.Create(ctx, u2); err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		if err := r.Delete(ctx, []int64{int64(u1.ID), int64(u2.ID)}); err != nil {
+			t.Fatalf("deleting user table: %v", err)
+		}
+
+		userDelete1, err := r.GetById(ctx, int64(u1.ID))
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Empty(t, userDelete1, "they should be equal")
+		assert.Nil(t, err)
+
+		userDelete2, err := r.GetById(ctx, int64(u1.ID))
+		if err != nil {
+			t.Fatalf("creating user table: %v", err)
+		}
+
+		assert.Empty(t, userDelete2, "they should be equal")
+		assert.Nil(t, err)
+	})
+
+}
+
+func TestUserPostgresRepoUpdate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{
+		QueryFields: true,
+	})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	u := interfaces.User{
+		Name: "John Doe",
+		Age:  5,
+	}
+
+	if err := r.Create(ctx, &u); err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	user, err := r.GetById(ctx, 7)
+	if err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	assert.Equal(t, "John Doe", user.Name, "they should be equal")
+
+	val := map[string]interface{}{
+		"name": "new name",
+	}
+
+	if err := r.Update(ctx, &u, val); err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	userUpdated, err := r.GetById(ctx, 7)
+	if err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	assert.Equal(t, "new name", userUpdated.Name, "they should be equal")
+}
+
+func TestUserPostgresRepoStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	t.Run("per day", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{}, interfaces.StatsQuery{})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, int64(6), stats.Total, "they should be equal")
+		assert.Equal(t, float64(43), stats.AverageAge, "they should be equal")
+		assert.Equal(t, 7, len(stats.AgeBuckets), "they should be equal")
+		assert.Equal(t, int64(0), stats.AgeBuckets[0].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[1].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[6].Count, "they should be equal")
+		assert.Equal(t, 6, len(stats.SignUps), "they should be equal")
+	})
+
+	t.Run("per week", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{}, interfaces.StatsQuery{Interval: interfaces.IntervalWeek})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, 2, len(stats.SignUps), "they should be equal")
+		assert.Equal(t, int64(5), stats.SignUps[0].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.SignUps[1].Count, "they should be equal")
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{AgeGte: 22, AgeLte: 45}, interfaces.StatsQuery{
+			AgeBoundaries: []uint8{35},
+		})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, int64(4), stats.Total, "they should be equal")
+		assert.Equal(t, int64(2), stats.AgeBuckets[0].Count, "they should be equal")
+		assert.Equal(t, int64(2), stats.AgeBuckets[1].Count, "they should be equal")
+	})
+
+	t.Run("utc days", func(t *testing.T) {
+		var stats *interfaces.UserStats
+
+		// one connection, the time zone is set for its session only.
+		err := db.Connection(func(conn *gorm.DB) error {
+			if err := conn.Exec("SET TimeZone = 'Asia/Tokyo'").Error; err != nil {
+				return err
+			}
+
+			r := repositories.NewUserRepoPostgres(conn)
+
+			late := time.Date(2024, 4, 9, 23, 0, 0, 0, time.UTC)
+			if err := r.Create(ctx, &interfaces.User{ID: 100, Name: "late", Age: 30, CreatedAt: late, UpdatedAt: late}); err != nil {
+				return err
+			}
+
+			var err error
+			stats, err = r.Stats(ctx, interfaces.Filters{IDs: []int64{100}}, interfaces.StatsQuery{})
+
+			return err
+		})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		if len(stats.SignUps) != 1 {
+			t.Fatalf("expected 1 period, got %d", len(stats.SignUps))
+		}
+
+		assert.Equal(t, "2024-04-09", stats.SignUps[0].Period.Format("2006-01-02"), "they should be equal")
+	})
+}
+
+func TestUserPostgresRepoFields(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+	fields := utils.WithFields(interfaces.FieldID, interfaces.FieldName)
+
+	t.Run("get by id", func(t *testing.T) {
+		user, err := r.GetById(ctx, 1, fields)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+
+		assert.Equal(t, "first", user.Name, "they should be equal")
+		assert.Equal(t, uint8(0), user.Age, "they should be equal")
+	})
+
+	t.Run("get all", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{}, fields)
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Equal(t, uint(6), users[0].ID, "they should be equal")
+		assert.True(t, users[0].CreatedAt.IsZero(), "they should be equal")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := r.GetById(ctx, 1, utils.WithFields("password"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestUserPostgresRepoGetByIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	users, missing, err := r.GetByIDs(ctx, []int64{5, 42, 2}, utils.WithFields(interfaces.FieldName))
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	assert.Equal(t, 2, len(users), "they should be equal")
+	assert.Equal(t, uint(5), users[0].ID, "they should be equal")
+	assert.Equal(t, "second", users[1].Name, "they should be equal")
+	assert.Equal(t, []int64{42}, missing, "they should be equal")
+}
+
+func TestUserPostgresRepoExplainGetAll(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	plan, err := r.(interfaces.Explainer).ExplainGetAll(ctx, interfaces.Filters{AgeGte: 22})
+	if err != nil {
+		t.Fatalf("explaining users: %v", err)
+	}
+
+	assert.Contains(t, plan, "Plan", "they should be equal")
+}
+
+func TestUserPostgresRepoMaxID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestPostgres(t, &gorm.Config{})
+
+	r := repositories.NewUserRepoPostgres(db)
+	setupPostgres(t, db, r)
+
+	id, err := r.(interfaces.MaxIDer).MaxID(ctx)
+	if err != nil {
+		t.Fatalf("getting max id: %v", err)
+	}
+
+	assert.Equal(t, int64(6), id, "they should be equal")
+}
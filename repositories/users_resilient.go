@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+type resilienceConfig struct {
+	timeouts     map[string]time.Duration
+	maxRetries   uint64
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	transient    func(error) bool
+}
+
+type ResilienceOption func(*resilienceConfig)
+
+func defaultResilienceConfig() resilienceConfig {
+	return resilienceConfig{
+		timeouts: map[string]time.Duration{
+			MethodGetById:  2 * time.Second,
+			MethodGetByIDs: 5 * time.Second,
+			MethodGetAll:   5 * time.Second,
+			MethodStats:    10 * time.Second,
+			MethodCreate:   5 * time.Second,
+			MethodUpdate:   5 * time.Second,
+			MethodDelete:   5 * time.Second,
+		},
+		maxRetries:   3,
+		initialDelay: 50 * time.Millisecond,
+		maxDelay:     time.Second,
+		transient: func(err error) bool {
+			return IsTransientMysql(err) || IsTransientPostgres(err) || IsTransientMongo(err)
+		},
+	}
+}
+
+// WithMethodTimeout bounds every call to method, retries included. Zero
+// leaves the call bounded by the caller context only.
+func WithMethodTimeout(method string, timeout time.Duration) ResilienceOption {
+	return func(c *resilienceConfig) {
+		c.timeouts[method] = timeout
+	}
+}
+
+// WithMaxRetries sets how many times a transient error is retried.
+func WithMaxRetries(retries uint64) ResilienceOption {
+	return func(c *resilienceConfig) {
+		c.maxRetries = retries
+	}
+}
+
+// WithRetryDelay sets the exponential backoff between retries, starting at
+// initial and capped at max, both randomised by half to spread the retries.
+func WithRetryDelay(initial, max time.Duration) ResilienceOption {
+	return func(c *resilienceConfig) {
+		c.initialDelay = initial
+		c.maxDelay = max
+	}
+}
+
+// WithTransientErrors replaces the classification of the errors worth
+// retrying, IsTransientMysql, IsTransientPostgres or IsTransientMongo by default.
+func WithTransientErrors(transient func(error) bool) ResilienceOption {
+	return func(c *resilienceConfig) {
+		c.transient = transient
+	}
+}
+
+// userRepoResilient applies a timeout per method and retries the idempotent
+// ones on transient errors. Create is never retried, nor are the calls made
+// inside a transaction, given with utils.WithTx or a Mongo session of the
+// context, since the database aborts the whole transaction on the errors
+// worth retrying.
+type userRepoResilient struct {
+	interfaces.UsersRepo
+	config resilienceConfig
+}
+
+func NewUserRepoResilient(repo interfaces.UsersRepo, opts ...ResilienceOption) interfaces.UsersRepo {
+	config := defaultResilienceConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	return &userRepoResilient{UsersRepo: repo, config: config}
+}
+
+func (r userRepoResilient) do(ctx context.Context, method string, retry bool, op func(context.Context) error) error {
+	if timeout := r.config.timeouts[method]; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if !retry || r.config.maxRetries == 0 {
+		return op(ctx)
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = r.config.initialDelay
+	b.MaxInterval = r.config.maxDelay
+	b.RandomizationFactor = 0.5
+	b.MaxElapsedTime = 0
+
+	return backoff.Retry(func() error {
+		err := op(ctx)
+		if err != nil && !r.config.transient(err) {
+			return backoff.Permanent(err)
+		}
+
+		return err
+	}, backoff.WithContext(backoff.WithMaxRetries(b, r.config.maxRetries), ctx))
+}
+
+func (r userRepoResilient) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	var user *interfaces.User
+	err := r.do(ctx, MethodGetById, !inTransaction(ctx, opts), func(ctx context.Context) (err error) {
+		user, err = r.UsersRepo.GetById(ctx, id, opts...)
+		return err
+	})
+
+	return user, err
+}
+
+func (r userRepoResilient) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	var (
+		users []*interfaces.User
+		total int64
+	)
+	err := r.do(ctx, MethodGetAll, !inTransaction(ctx, opts), func(ctx context.Context) (err error) {
+		users, total, err = r.UsersRepo.GetAll(ctx, filters, opts...)
+		return err
+	})
+
+	return users, total, err
+}
+
+func (r userRepoResilient) GetByIDs(ctx context.Context, ids []int64, opts ...utils.Options) ([]*interfaces.User, []int64, error) {
+	var (
+		users   []*interfaces.User
+		missing []int64
+	)
+	err := r.do(ctx, MethodGetByIDs, !inTransaction(ctx, opts), func(ctx context.Context) (err error) {
+		users, missing, err = r.UsersRepo.GetByIDs(ctx, ids, opts...)
+		return err
+	})
+
+	return users, missing, err
+}
+
+func (r userRepoResilient) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	return r.do(ctx, MethodCreate, false, func(ctx context.Context) error {
+		return r.UsersRepo.Create(ctx, user, opts...)
+	})
+}
+
+func (r userRepoResilient) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	return r.do(ctx, MethodUpdate, !inTransaction(ctx, opts), func(ctx context.Context) error {
+		return r.UsersRepo.Update(ctx, user, vals, opts...)
+	})
+}
+
+func (r userRepoResilient) Delete(ctx context.Context, ids []int64, opts ...utils.Options) error {
+	return r.do(ctx, MethodDelete, !inTransaction(ctx, opts), func(ctx context.Context) error {
+		return r.UsersRepo.Delete(ctx, ids, opts...)
+	})
+}
+
+func (r userRepoResilient) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	var stats *interfaces.UserStats
+	err := r.do(ctx, MethodStats, !inTransaction(ctx, opts), func(ctx context.Context) (err error) {
+		stats, err = r.UsersRepo.Stats(ctx, filters, query, opts...)
+		return err
+	})
+
+	return stats, err
+}
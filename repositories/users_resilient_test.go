@@ -0,0 +1,156 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+// flakyUsersRepo fails the first calls of GetById, Create and Update with err.
+type flakyUsersRepo struct {
+	*mocks.RecordingUsersRepo
+	failures int
+	err      error
+}
+
+// fail returns err for the first failures calls of method, as recorded by
+// the embedded repository, and the result of the call afterwards.
+func (f *flakyUsersRepo) fail(method string, result error) error {
+	if result == nil && len(f.CallsTo(method)) <= f.failures {
+		return f.err
+	}
+
+	return result
+}
+
+func (f *flakyUsersRepo) GetById(ctx context.Context, id int64, opts ...utils.Options) (*interfaces.User, error) {
+	user, err := f.RecordingUsersRepo.GetById(ctx, id, opts...)
+	if err := f.fail(repositories.MethodGetById, err); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (f *flakyUsersRepo) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	return f.fail(repositories.MethodCreate, f.RecordingUsersRepo.Create(ctx, user, opts...))
+}
+
+func (f *flakyUsersRepo) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	return f.fail(repositories.MethodUpdate, f.RecordingUsersRepo.Update(ctx, user, vals, opts...))
+}
+
+// blockingUsersRepo blocks Stats until its context is done.
+type blockingUsersRepo struct {
+	*mocks.RecordingUsersRepo
+}
+
+func (b *blockingUsersRepo) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func TestUserRepoResilient(t *testing.T) {
+	ctx := context.Background()
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		fake := &flakyUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(interfaces.User{ID: 1}), failures: 2, err: deadlock}
+		r := repositories.NewUserRepoResilient(fake, repositories.WithRetryDelay(time.Millisecond, time.Millisecond))
+
+		user, err := r.GetById(ctx, 1)
+		assert.Nil(t, err, "should be nil")
+		assert.Equal(t, uint(1), user.ID, "they should be equal")
+		assert.Equal(t, 3, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+	})
+
+	t.Run("retries are bounded", func(t *testing.T) {
+		fake := &flakyUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(), failures: 10, err: deadlock}
+		r := repositories.NewUserRepoResilient(fake,
+			repositories.WithMaxRetries(2),
+			repositories.WithRetryDelay(time.Millisecond, time.Millisecond),
+		)
+
+		err := r.Update(ctx, &interfaces.User{ID: 1}, map[string]interface{}{"name": "john"})
+		assert.Equal(t, deadlock, err, "they should be equal")
+		assert.Equal(t, 3, len(fake.CallsTo(repositories.MethodUpdate)), "they should be equal")
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		fake := &flakyUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(), failures: 1, err: gorm.ErrInvalidData}
+		r := repositories.NewUserRepoResilient(fake, repositories.WithRetryDelay(time.Millisecond, time.Millisecond))
+
+		_, err := r.GetById(ctx, 1)
+		assert.Equal(t, gorm.ErrInvalidData, err, "they should be equal")
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+	})
+
+	t.Run("creates and transactions are not retried", func(t *testing.T) {
+		fake := &flakyUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(), failures: 1, err: deadlock}
+		r := repositories.NewUserRepoResilient(fake, repositories.WithRetryDelay(time.Millisecond, time.Millisecond))
+
+		err := r.Create(ctx, &interfaces.User{Name: "john"})
+		assert.Equal(t, deadlock, err, "they should be equal")
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodCreate)), "they should be equal")
+
+		err = r.Update(ctx, &interfaces.User{ID: 1}, map[string]interface{}{"name": "john"}, utils.WithTx(&gorm.DB{}))
+		assert.Equal(t, deadlock, err, "they should be equal")
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodUpdate)), "they should be equal")
+	})
+
+	t.Run("mongo transactions are not retried", func(t *testing.T) {
+		fake := &flakyUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(), failures: 1, err: deadlock}
+		r := repositories.NewUserRepoResilient(fake, repositories.WithRetryDelay(time.Millisecond, time.Millisecond))
+
+		// the client connects lazily, the session is never used.
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:1"))
+		if err != nil {
+			t.Fatalf("creating client: %v", err)
+		}
+		defer client.Disconnect(ctx)
+
+		session, err := client.StartSession()
+		if err != nil {
+			t.Fatalf("starting session: %v", err)
+		}
+		defer session.EndSession(ctx)
+
+		_, err = r.GetById(mongo.NewSessionContext(ctx, session), 1)
+		assert.Equal(t, deadlock, err, "they should be equal")
+		assert.Equal(t, 1, len(fake.CallsTo(repositories.MethodGetById)), "they should be equal")
+	})
+
+	t.Run("calls are bounded by the method timeout", func(t *testing.T) {
+		r := repositories.NewUserRepoResilient(&blockingUsersRepo{mocks.NewRecordingUsersRepo()},
+			repositories.WithMethodTimeout(repositories.MethodStats, 10*time.Millisecond),
+		)
+
+		_, err := r.Stats(ctx, interfaces.Filters{}, interfaces.StatsQuery{})
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "should be true")
+	})
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, repositories.IsTransientMysql(&mysql.MySQLError{Number: 1213}), "should be true")
+	assert.True(t, repositories.IsTransientMysql(fmt.Errorf("update: %w", &mysql.MySQLError{Number: 1205})), "should be true")
+	assert.False(t, repositories.IsTransientMysql(&mysql.MySQLError{Number: 1062}), "should be false")
+
+	assert.True(t, repositories.IsTransientMongo(mongo.CommandError{Code: 11600, Labels: []string{"RetryableWriteError"}}), "should be true")
+	assert.True(t, repositories.IsTransientMongo(mongo.CommandError{Code: 10107}), "should be true")
+	assert.False(t, repositories.IsTransientMongo(mongo.CommandError{Code: 11000}), "should be false")
+	assert.False(t, repositories.IsTransientMongo(errors.New("boom")), "should be false")
+}
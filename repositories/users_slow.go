@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+)
+
+const explainTimeout = 10 * time.Second
+
+// ErrExplainSkipped is the PlanErr of the slow queries reported without a
+// plan, the explains running or recently run being limited.
+var ErrExplainSkipped = errors.New("explain skipped")
+
+// SlowQuery describes a GetAll call that took longer than the threshold,
+// Plan holds the explain of its query when the backend supports it.
+type SlowQuery struct {
+	Filters  interfaces.Filters
+	Duration time.Duration
+	Plan     string
+	PlanErr  error
+}
+
+type slowQueryConfig struct {
+	report          func(context.Context, SlowQuery)
+	maxExplains     int
+	explainInterval time.Duration
+}
+
+type SlowQueryOption func(*slowQueryConfig)
+
+func defaultSlowQueryConfig() slowQueryConfig {
+	return slowQueryConfig{
+		report: func(ctx context.Context, q SlowQuery) {
+			slog.WarnContext(ctx, "slow query",
+				slog.Any("filters", q.Filters),
+				slog.Duration("duration", q.Duration),
+				slog.String("plan", q.Plan),
+				slog.Any("plan_error", q.PlanErr),
+			)
+		},
+		maxExplains:     1,
+		explainInterval: time.Minute,
+	}
+}
+
+// WithSlowQueryReport replaces the default report, a warn log. The queries
+// reported without a plan are reported by GetAll itself, report must not
+// block.
+func WithSlowQueryReport(report func(context.Context, SlowQuery)) SlowQueryOption {
+	return func(c *slowQueryConfig) {
+		c.report = report
+	}
+}
+
+// WithMaxExplains sets how many explains may run at once, the slow queries
+// found while they run are reported without a plan.
+func WithMaxExplains(max int) SlowQueryOption {
+	return func(c *slowQueryConfig) {
+		c.maxExplains = max
+	}
+}
+
+// WithExplainInterval sets how long the queries of the same shape, setting
+// the same filters, are reported without a plan once one was explained.
+func WithExplainInterval(interval time.Duration) SlowQueryOption {
+	return func(c *slowQueryConfig) {
+		c.explainInterval = interval
+	}
+}
+
+// userRepoSlowQuery explains the GetAll calls slower than threshold in the
+// background and reports them. It must wrap the backend repository directly,
+// since the other decorators do not implement interfaces.Explainer.
+type userRepoSlowQuery struct {
+	interfaces.UsersRepo
+	threshold time.Duration
+	config    slowQueryConfig
+	limiter   *explainLimiter
+}
+
+func NewUserRepoSlowQuery(repo interfaces.UsersRepo, threshold time.Duration, opts ...SlowQueryOption) interfaces.UsersRepo {
+	config := defaultSlowQueryConfig()
+	for _, fn := range opts {
+		fn(&config)
+	}
+
+	return &userRepoSlowQuery{
+		UsersRepo: repo,
+		threshold: threshold,
+		config:    config,
+		limiter: &explainLimiter{
+			running:  make(chan struct{}, max(config.maxExplains, 1)),
+			interval: config.explainInterval,
+			last:     map[string]time.Time{},
+		},
+	}
+}
+
+func (r userRepoSlowQuery) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	start := time.Now()
+	users, total, err := r.UsersRepo.GetAll(ctx, filters, opts...)
+
+	if duration := time.Since(start); duration > r.threshold {
+		normalized, verr := filters.Normalize()
+		if verr == nil {
+			if r.limiter.acquire(queryShape(normalized)) {
+				// the explain runs after the call, out of its transaction.
+				ctx, opts := outsideTransaction(context.WithoutCancel(ctx), opts)
+				go r.explain(ctx, normalized, duration, opts)
+			} else {
+				r.config.report(ctx, SlowQuery{Filters: normalized, Duration: duration, PlanErr: ErrExplainSkipped})
+			}
+		}
+	}
+
+	return users, total, err
+}
+
+func (r userRepoSlowQuery) explain(ctx context.Context, filters interfaces.Filters, duration time.Duration, opts []utils.Options) {
+	defer r.limiter.release()
+
+	q := SlowQuery{Filters: filters, Duration: duration}
+
+	if explainer, ok := r.UsersRepo.(interfaces.Explainer); ok {
+		ctx, cancel := context.WithTimeout(ctx, explainTimeout)
+		defer cancel()
+
+		q.Plan, q.PlanErr = explainer.ExplainGetAll(ctx, filters, opts...)
+	}
+
+	r.config.report(ctx, q)
+}
+
+// explainLimiter bounds the explains, which load a database already slow and
+// on Mongo run the query again: a few at once, and one per shape of query
+// every interval.
+type explainLimiter struct {
+	running  chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// acquire reports whether a query of shape may be explained, release must
+// be called once it is.
+func (l *explainLimiter) acquire(shape string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if last, ok := l.last[shape]; ok && time.Since(last) < l.interval {
+		return false
+	}
+
+	select {
+	case l.running <- struct{}{}:
+	default:
+		return false
+	}
+
+	now := time.Now()
+	for s, last := range l.last {
+		if now.Sub(last) >= l.interval {
+			delete(l.last, s)
+		}
+	}
+	l.last[shape] = now
+
+	return true
+}
+
+func (l *explainLimiter) release() {
+	<-l.running
+}
+
+// queryShape names the filters set, the queries of the same shape sharing
+// their plan whatever the values.
+func queryShape(filters interfaces.Filters) string {
+	return fmt.Sprintf("ids=%t age_gte=%t age_lte=%t order=%s", len(filters.IDs) > 0, filters.AgeGte != 0, filters.AgeLte != 0, filters.OrderBy)
+}
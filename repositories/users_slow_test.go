@@ -0,0 +1,132 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/mocks"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// explainUsersRepo delays GetAll and explains it with a fixed plan.
+type explainUsersRepo struct {
+	*mocks.RecordingUsersRepo
+	delay time.Duration
+}
+
+func (e *explainUsersRepo) GetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) ([]*interfaces.User, int64, error) {
+	time.Sleep(e.delay)
+
+	return e.RecordingUsersRepo.GetAll(ctx, filters, opts...)
+}
+
+func (e *explainUsersRepo) ExplainGetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) (string, error) {
+	if utils.HasTx(opts...) {
+		return "", errors.New("transaction already finished")
+	}
+
+	return "full scan", nil
+}
+
+func TestUserRepoSlowQuery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("slow queries are explained", func(t *testing.T) {
+		reports := make(chan repositories.SlowQuery, 1)
+		fake := &explainUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(fixtures.NewUser(fixtures.WithID(1), fixtures.WithAge(30))), delay: 20 * time.Millisecond}
+		r := repositories.NewUserRepoSlowQuery(fake, 10*time.Millisecond, repositories.WithSlowQueryReport(func(ctx context.Context, q repositories.SlowQuery) {
+			reports <- q
+		}))
+
+		users, _, err := r.GetAll(ctx, interfaces.Filters{AgeGte: 20})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, 1, len(users), "they should be equal")
+
+		select {
+		case q := <-reports:
+			assert.Equal(t, "full scan", q.Plan, "they should be equal")
+			assert.Equal(t, uint8(20), q.Filters.AgeGte, "they should be equal")
+			assert.Equal(t, utils.Limit, q.Filters.Limit, "they should be equal")
+			assert.True(t, q.Duration >= 20*time.Millisecond, "they should be equal")
+		case <-time.After(time.Second):
+			t.Fatal("slow query not reported")
+		}
+	})
+
+	t.Run("explained out of the transaction", func(t *testing.T) {
+		reports := make(chan repositories.SlowQuery, 1)
+		fake := &explainUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(fixtures.NewUser(fixtures.WithID(1), fixtures.WithAge(30))), delay: 20 * time.Millisecond}
+		r := repositories.NewUserRepoSlowQuery(fake, 10*time.Millisecond, repositories.WithSlowQueryReport(func(ctx context.Context, q repositories.SlowQuery) {
+			reports <- q
+		}))
+
+		if _, _, err := r.GetAll(ctx, interfaces.Filters{AgeGte: 20}, utils.WithTx(&gorm.DB{}), utils.WithLock); err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		select {
+		case q := <-reports:
+			assert.Nil(t, q.PlanErr, "should be nil")
+			assert.Equal(t, "full scan", q.Plan, "they should be equal")
+		case <-time.After(time.Second):
+			t.Fatal("slow query not reported")
+		}
+	})
+
+	t.Run("fast queries are not reported", func(t *testing.T) {
+		reports := make(chan repositories.SlowQuery, 1)
+		fake := &explainUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(fixtures.NewUser(fixtures.WithID(1), fixtures.WithAge(30)))}
+		r := repositories.NewUserRepoSlowQuery(fake, time.Second, repositories.WithSlowQueryReport(func(ctx context.Context, q repositories.SlowQuery) {
+			reports <- q
+		}))
+
+		if _, _, err := r.GetAll(ctx, interfaces.Filters{}); err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		select {
+		case <-reports:
+			t.Fatal("fast query reported")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("explains are limited", func(t *testing.T) {
+		reports := make(chan repositories.SlowQuery, 3)
+		fake := &explainUsersRepo{RecordingUsersRepo: mocks.NewRecordingUsersRepo(fixtures.NewUser(fixtures.WithID(1), fixtures.WithAge(30))), delay: 20 * time.Millisecond}
+		r := repositories.NewUserRepoSlowQuery(fake, 10*time.Millisecond, repositories.WithSlowQueryReport(func(ctx context.Context, q repositories.SlowQuery) {
+			reports <- q
+		}))
+
+		// same shape as the first one, then another shape.
+		for _, filters := range []interfaces.Filters{{AgeGte: 20}, {AgeGte: 30}, {AgeLte: 30}} {
+			if _, _, err := r.GetAll(ctx, filters); err != nil {
+				t.Fatalf("get users: %v", err)
+			}
+
+			select {
+			case q := <-reports:
+				switch {
+				case filters.AgeGte == 30:
+					assert.Equal(t, repositories.ErrExplainSkipped, q.PlanErr, "they should be equal")
+					assert.Empty(t, q.Plan, "should be empty")
+				default:
+					assert.Equal(t, "full scan", q.Plan, "they should be equal")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("slow query not reported")
+			}
+		}
+	})
+}
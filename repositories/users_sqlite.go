@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sqlite.sql
+var sqliteSchema string
+
+const sqliteDate = "2006-01-02"
+
+// userRepoSQLite reuses the queries of userRepoMysql, SQLite understands
+// them as they are. It only differs where dates are involved: SQLite stores
+// them as text, so every date is written and compared in UTC for the text
+// order to match the time order, see sqliteDialect.
+type userRepoSQLite struct {
+	userRepoMysql
+}
+
+// NewUserRepoSQLite returns the repository of a database opened with
+// github.com/glebarez/sqlite, whose schema was created by MigrateSQLite.
+func NewUserRepoSQLite(db *gorm.DB) interfaces.UsersRepo {
+	db = db.Session(&gorm.Session{NowFunc: func() time.Time {
+		return time.Now().UTC()
+	}})
+
+	return &userRepoSQLite{userRepoMysql{gormRepo[interfaces.User, int64]{db: db, dialect: sqliteDialect, entity: interfaces.UserEntity}}}
+}
+
+// MigrateSQLite creates the users table unless it exists.
+func MigrateSQLite(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Exec(sqliteSchema).Error
+}
+
+// ExplainGetAll returns the EXPLAIN QUERY PLAN of the query run by GetAll,
+// one step per line.
+func (r userRepoSQLite) ExplainGetAll(ctx context.Context, filters interfaces.Filters, opts ...utils.Options) (string, error) {
+	q, err := compileFilters(r.dialect, filters)
+	if err != nil {
+		return "", err
+	}
+
+	var users []*interfaces.User
+	stmt := q.Query(ctx, r.db, opts...).
+		Scopes(q.Page).
+		Session(&gorm.Session{DryRun: true}).
+		Find(&users).
+		Statement
+
+	var steps []struct {
+		Detail string
+	}
+
+	err = r.db.
+		WithContext(ctx).
+		Raw("EXPLAIN QUERY PLAN "+stmt.SQL.String(), stmt.Vars...).
+		Scan(&steps).
+		Error
+	if err != nil {
+		return "", err
+	}
+
+	plan := make([]string, len(steps))
+	for i, s := range steps {
+		plan[i] = s.Detail
+	}
+
+	return strings.Join(plan, "\n"), nil
+}
+
+// Create stores the times in UTC, SQLite comparing them as text. The zero
+// times default to now in UTC here, the transactions given by utils.WithTx
+// do not use the UTC clock of the session.
+func (r userRepoSQLite) Create(ctx context.Context, user *interfaces.User, opts ...utils.Options) error {
+	now := time.Now().UTC()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	user.CreatedAt = user.CreatedAt.UTC()
+	user.UpdatedAt = user.UpdatedAt.UTC()
+
+	return r.userRepoMysql.Create(ctx, user, opts...)
+}
+
+// Update stores the times of vals in UTC, as Create does, SQLite comparing
+// them as text. updated_at defaults to now in UTC rather than the local time
+// gorm would set.
+func (r userRepoSQLite) Update(ctx context.Context, user *interfaces.User, vals map[string]interface{}, opts ...utils.Options) error {
+	utc := make(map[string]interface{}, len(vals)+1)
+	for k, v := range vals {
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC()
+		}
+		utc[k] = v
+	}
+
+	if _, ok := utc[interfaces.FieldUpdatedAt]; !ok {
+		utc[interfaces.FieldUpdatedAt] = time.Now().UTC()
+	}
+
+	return r.userRepoMysql.Update(ctx, user, utc, opts...)
+}
+
+func (r userRepoSQLite) Stats(ctx context.Context, filters interfaces.Filters, query interfaces.StatsQuery, opts ...utils.Options) (*interfaces.UserStats, error) {
+	q, err := compileFilters(r.dialect, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err = query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &interfaces.UserStats{AgeBuckets: query.AgeBuckets()}
+
+	var totals struct {
+		Total      int64
+		AverageAge float64
+	}
+
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select("COUNT(*) AS total, COALESCE(AVG(age), 0) AS average_age").
+		Scan(&totals).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Total, stats.AverageAge = totals.Total, totals.AverageAge
+
+	bucket, args := ageBucketCase(query.AgeBoundaries)
+
+	var ages []struct {
+		Bucket int
+		Count  int64
+	}
+
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select("("+bucket+") AS bucket, COUNT(*) AS count", args...).
+		Group("bucket").
+		Scan(&ages).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range ages {
+		stats.AgeBuckets[a.Bucket].Count = a.Count
+	}
+
+	period := "DATE(created_at)"
+	if query.Interval == interfaces.IntervalWeek {
+		period = "DATE(created_at, 'weekday 0', '-6 days')"
+	}
+
+	// the dates computed by SQLite are text, they are parsed here.
+	var signUps []struct {
+		Period string
+		Count  int64
+	}
+
+	err = q.Query(ctx, r.db, opts...).
+		Model(&interfaces.User{}).
+		Select(period + " AS period, COUNT(*) AS count").
+		Group("period").
+		Order("period").
+		Scan(&signUps).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range signUps {
+		period, err := time.Parse(sqliteDate, s.Period)
+		if err != nil {
+			return nil, fmt.Errorf("parsing period %q: %w", s.Period, err)
+		}
+
+		stats.SignUps = append(stats.SignUps, interfaces.SignUps{Period: period, Count: s.Count})
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,391 @@
+package repositories_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/repositories"
+	"repos/utils"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteSeed holds the canonical fixtures with their wall clock in UTC, so
+// the periods of the stats do not depend on the local time zone.
+// sqliteWindow covers all of them.
+var (
+	sqliteSeed   = inUTC(fixtures.Users())
+	sqliteWindow = time.Date(2024, 4, 16, 0, 0, 0, 0, time.UTC)
+)
+
+func inUTC(users []interfaces.User) []interfaces.User {
+	wall := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+
+	for i := range users {
+		users[i].CreatedAt = wall(users[i].CreatedAt)
+		users[i].UpdatedAt = wall(users[i].UpdatedAt)
+	}
+
+	return users
+}
+
+// NewTestSQLite opens a database file in a temporary directory with the seed users.
+func NewTestSQLite(ctx context.Context, t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "users.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+
+	if err := repositories.MigrateSQLite(ctx, db); err != nil {
+		t.Fatalf("creating user table: %v", err)
+	}
+
+	fixtures.Setup(t, repositories.NewUserRepoSQLite(db), sqliteSeed...)
+
+	return db
+}
+
+func TestUserSQLiteRepoGetByID(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+
+	user, err := r.GetById(ctx, 3)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	assert.Equal(t, "third", user.Name, "they should be equal")
+	assert.True(t, sqliteSeed[2].CreatedAt.Equal(user.CreatedAt), "they should be equal")
+
+	user, err = r.GetById(ctx, 42)
+	assert.Nil(t, err, "should be nil")
+	assert.Empty(t, user, "they should be equal")
+}
+
+func TestUserSQLiteRepoGetAll(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+
+	t.Run("limit and offset", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{Offset: 1, Limit: 2, CreatedAtLte: sqliteWindow})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, 2, len(users), "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+		assert.Equal(t, uint(5), users[0].ID, "they should be equal")
+		assert.Equal(t, uint(4), users[1].ID, "they should be equal")
+	})
+
+	t.Run("default window", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, 6, len(users), "they should be equal")
+		assert.Equal(t, int64(6), total, "they should be equal")
+	})
+
+	t.Run("between gte and lte", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{
+			CreatedAtGte: time.Date(2024, 4, 13, 0, 0, 0, 0, time.Local),
+			CreatedAtLte: time.Date(2024, 4, 15, 0, 0, 0, 0, time.Local),
+		})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, 2, len(users), "they should be equal")
+		assert.Equal(t, int64(2), total, "they should be equal")
+	})
+
+	t.Run("order by age", func(t *testing.T) {
+		users, _, err := r.GetAll(ctx, interfaces.Filters{OrderBy: interfaces.OrderByAge, CreatedAtLte: sqliteWindow})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, uint8(66), users[0].Age, "they should be equal")
+		assert.Equal(t, uint8(55), users[1].Age, "they should be equal")
+	})
+
+	t.Run("order by name", func(t *testing.T) {
+		users, _, err := r.GetAll(ctx, interfaces.Filters{OrderBy: interfaces.OrderByName, CreatedAtLte: sqliteWindow})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, "third", users[0].Name, "they should be equal")
+		assert.Equal(t, "six", users[1].Name, "they should be equal")
+	})
+
+	t.Run("age range", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{AgeGte: 22, AgeLte: 45, CreatedAtLte: sqliteWindow})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, 4, len(users), "they should be equal")
+		assert.Equal(t, int64(4), total, "they should be equal")
+	})
+
+	t.Run("by ids", func(t *testing.T) {
+		users, total, err := r.GetAll(ctx, interfaces.Filters{IDs: []int64{3, 4}, CreatedAtLte: sqliteWindow})
+		if err != nil {
+			t.Fatalf("get users: %v", err)
+		}
+
+		assert.Equal(t, 2, len(users), "they should be equal")
+		assert.Equal(t, int64(2), total, "they should be equal")
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		_, _, err := r.GetAll(ctx, interfaces.Filters{Limit: -1})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestUserSQLiteRepoWrites(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+
+	u := &interfaces.User{Name: "John Doe", Age: 5}
+	if err := r.Create(ctx, u); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	assert.Equal(t, uint(7), u.ID, "they should be equal")
+
+	users, total, err := r.GetAll(ctx, interfaces.Filters{CreatedAtLte: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	assert.Equal(t, int64(1), total, "they should be equal")
+	assert.Equal(t, uint(7), users[0].ID, "they should be equal")
+
+	if err := r.Update(ctx, u, map[string]interface{}{"name": "new name"}); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+
+	user, err := r.GetById(ctx, 7)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	assert.Equal(t, "new name", user.Name, "they should be equal")
+
+	if err := r.Delete(ctx, []int64{7, 1}); err != nil {
+		t.Fatalf("deleting users: %v", err)
+	}
+
+	_, missing, err := r.GetByIDs(ctx, []int64{1, 2, 7})
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	assert.Equal(t, []int64{1, 7}, missing, "they should be equal")
+}
+
+func TestUserSQLiteRepoStats(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+
+	t.Run("per day", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{CreatedAtLte: sqliteWindow}, interfaces.StatsQuery{})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, int64(6), stats.Total, "they should be equal")
+		assert.Equal(t, float64(43), stats.AverageAge, "they should be equal")
+		assert.Equal(t, 7, len(stats.AgeBuckets), "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[1].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.AgeBuckets[6].Count, "they should be equal")
+		assert.Equal(t, 6, len(stats.SignUps), "they should be equal")
+		assert.Equal(t, time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC), stats.SignUps[0].Period, "they should be equal")
+	})
+
+	t.Run("per week", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{CreatedAtLte: sqliteWindow}, interfaces.StatsQuery{Interval: interfaces.IntervalWeek})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, 2, len(stats.SignUps), "they should be equal")
+		assert.Equal(t, time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC), stats.SignUps[0].Period, "they should be equal")
+		assert.Equal(t, int64(5), stats.SignUps[0].Count, "they should be equal")
+		assert.Equal(t, int64(1), stats.SignUps[1].Count, "they should be equal")
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		stats, err := r.Stats(ctx, interfaces.Filters{AgeGte: 22, AgeLte: 45, CreatedAtLte: sqliteWindow}, interfaces.StatsQuery{
+			AgeBoundaries: []uint8{35},
+		})
+		if err != nil {
+			t.Fatalf("getting stats: %v", err)
+		}
+
+		assert.Equal(t, int64(4), stats.Total, "they should be equal")
+		assert.Equal(t, int64(2), stats.AgeBuckets[0].Count, "they should be equal")
+		assert.Equal(t, int64(2), stats.AgeBuckets[1].Count, "they should be equal")
+	})
+}
+
+func TestUserSQLiteRepoFields(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+
+	user, err := r.GetById(ctx, 1, utils.WithFields(interfaces.FieldID, interfaces.FieldName))
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	assert.Equal(t, "first", user.Name, "they should be equal")
+	assert.Equal(t, uint8(0), user.Age, "they should be equal")
+
+	users, missing, err := r.GetByIDs(ctx, []int64{5, 42, 2}, utils.WithFields(interfaces.FieldName))
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	assert.Equal(t, 2, len(users), "they should be equal")
+	assert.Equal(t, uint(5), users[0].ID, "they should be equal")
+	assert.Equal(t, "second", users[1].Name, "they should be equal")
+	assert.Equal(t, []int64{42}, missing, "they should be equal")
+
+	_, err = r.GetById(ctx, 1, utils.WithFields("password"))
+	assert.Error(t, err)
+}
+
+func TestUserSQLiteRepoFieldsIgnoredByWrites(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+	fields := utils.WithFields(interfaces.FieldID, interfaces.FieldName)
+
+	if err := r.Create(ctx, &interfaces.User{ID: 100, Name: "john", Age: 30}, fields); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	if err := r.Update(ctx, &interfaces.User{ID: 100}, map[string]interface{}{interfaces.FieldAge: 31}, fields); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+
+	user, err := r.GetById(ctx, 100)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	assert.Equal(t, "john", user.Name, "they should be equal")
+	assert.Equal(t, uint8(31), user.Age, "they should be equal")
+}
+
+func TestUserSQLiteRepoExplainGetAll(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+
+	plan, err := r.(interfaces.Explainer).ExplainGetAll(ctx, interfaces.Filters{AgeGte: 22})
+	if err != nil {
+		t.Fatalf("explaining users: %v", err)
+	}
+
+	assert.Contains(t, plan, "SCAN users", "they should be equal")
+
+	id, err := r.(interfaces.MaxIDer).MaxID(ctx)
+	if err != nil {
+		t.Fatalf("getting max id: %v", err)
+	}
+
+	assert.Equal(t, int64(6), id, "they should be equal")
+}
+
+func TestUserSQLiteRepoConformance(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+
+	created := sqliteWindow.Add(-time.Hour)
+	samples := []*interfaces.User{
+		{ID: 7, Name: "seven", Age: 17, CreatedAt: created, UpdatedAt: created},
+		{ID: 8, Name: "eight", Age: 18, CreatedAt: created, UpdatedAt: created},
+		{ID: 9, Name: "nine", Age: 19, CreatedAt: created, UpdatedAt: created},
+	}
+
+	testRepoConformance[interfaces.User, int64, interfaces.Filters](t, r, interfaces.UserEntity, samples, 42, interfaces.Filters{
+		CreatedAtLte: sqliteWindow,
+		IDs:          []int64{7, 8, 9},
+	})
+}
+
+func TestUserSQLiteRepoUpdateTimes(t *testing.T) {
+	ctx := context.Background()
+	r := repositories.NewUserRepoSQLite(NewTestSQLite(ctx, t))
+
+	// 2024-04-09 20:00 in UTC, a day later as text in its own zone.
+	createdAt := time.Date(2024, 4, 10, 1, 0, 0, 0, time.FixedZone("+05:00", 5*60*60))
+	if err := r.Update(ctx, &interfaces.User{ID: 1}, map[string]interface{}{interfaces.FieldCreatedAt: createdAt}); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+
+	users, _, err := r.GetAll(ctx, interfaces.Filters{
+		IDs:          []int64{1},
+		CreatedAtGte: time.Date(2024, 4, 9, 21, 0, 0, 0, time.UTC),
+		CreatedAtLte: time.Date(2024, 4, 12, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	assert.Empty(t, users, "should be empty")
+
+	user, err := r.GetById(ctx, 1)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	assert.True(t, createdAt.Equal(user.CreatedAt), "they should be equal")
+}
+
+func TestUserSQLiteRepoCreateTimes(t *testing.T) {
+	ctx := context.Background()
+	db := NewTestSQLite(ctx, t)
+	r := repositories.NewUserRepoSQLite(db)
+
+	// the caller's transaction does not use the UTC clock of the repository.
+	local := time.Local
+	time.Local = time.FixedZone("+05:00", 5*60*60)
+	defer func() { time.Local = local }()
+
+	user := &interfaces.User{ID: 100, Name: "john", Age: 20}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return r.Create(ctx, user, utils.WithTx(tx))
+	})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	now := time.Now().UTC()
+	users, _, err := r.GetAll(ctx, interfaces.Filters{
+		IDs:          []int64{100},
+		CreatedAtGte: now.Add(-time.Minute),
+		CreatedAtLte: now.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("get users: %v", err)
+	}
+
+	assert.Equal(t, 1, len(users), "they should be equal")
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"testing"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkIDs(t *testing.T) {
+	ids := make([]int64, 0, utils.IDsChunk*2+1)
+	for i := 0; i < utils.IDsChunk*2+1; i++ {
+		ids = append(ids, int64(i))
+	}
+
+	chunks := chunkIDs(append(ids, 1, 2, 3))
+
+	assert.Equal(t, 3, len(chunks), "they should be equal")
+	assert.Equal(t, utils.IDsChunk, len(chunks[0]), "they should be equal")
+	assert.Equal(t, []int64{int64(utils.IDsChunk * 2)}, chunks[2], "they should be equal")
+}
+
+func TestSortByIDs(t *testing.T) {
+	users := []*interfaces.User{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	sorted, missing := sortByIDs([]int64{3, 9, 1, 3, 2}, users)
+
+	assert.Equal(t, []*interfaces.User{{ID: 3}, {ID: 1}, {ID: 2}}, sorted, "they should be equal")
+	assert.Equal(t, []int64{9}, missing, "they should be equal")
+}
+
+func TestWithIDField(t *testing.T) {
+	t.Run("all fields", func(t *testing.T) {
+		opts, err := withIDField(nil)
+
+		assert.Nil(t, err)
+		assert.Empty(t, utils.Fields(opts...), "they should be equal")
+	})
+
+	t.Run("id added", func(t *testing.T) {
+		opts, err := withIDField([]utils.Options{utils.WithFields(interfaces.FieldName)})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"id", "name"}, utils.Fields(opts...), "they should be equal")
+	})
+}
@@ -0,0 +1,374 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"repos/interfaces"
+	"repos/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// watchInterval is how often the gorm repositories poll the outbox.
+	watchInterval = 500 * time.Millisecond
+	// watchGapWindow is how long the gorm repositories look for a missing
+	// sequence of the outbox, a transaction committed after the following
+	// ones, before giving it up as rolled back.
+	watchGapWindow = 5 * time.Minute
+	// watchMaxGaps is how far before the last sequence read a missing one is
+	// looked for, bounding the gaps tracked and the size of the tokens.
+	watchMaxGaps = 100
+	// mongoNamespaceNotFound is the error code of a missing collection.
+	mongoNamespaceNotFound = 26
+)
+
+type resumeKey struct{}
+
+// WithResumeToken returns a context watching the changes following the one
+// of token, a UserChange.Token of a previous watch, rather than the changes
+// made from now on.
+func WithResumeToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, resumeKey{}, token)
+}
+
+func resumeToken(ctx context.Context) string {
+	token, _ := ctx.Value(resumeKey{}).(string)
+
+	return token
+}
+
+// sendChange delivers the change unless ctx is done first.
+func sendChange(ctx context.Context, changes chan<- interfaces.UserChange, change interfaces.UserChange) bool {
+	select {
+	case changes <- change:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Watch polls the outbox written by NewUserRepoOutbox, the changes made
+// without it are not seen, nor those purged by the Relay when resuming from
+// an older token. The sequences are not committed in order: a missing one is
+// looked for again at every poll for watchGapWindow, so the changes of a
+// user arrive in order but those of different users may not. Without a
+// token the watch starts after the last sequence and looks for the missing
+// ones before it. The token of a change holds the last sequence read and the
+// missing ones.
+func (r userRepoMysql) Watch(ctx context.Context, filters interfaces.Filters) (<-chan interfaces.UserChange, error) {
+	if err := filters.ValidateWatch(); err != nil {
+		return nil, err
+	}
+
+	var cursor *outboxCursor
+	if token := resumeToken(ctx); token != "" {
+		var err error
+		if cursor, err = parseOutboxCursor(token, time.Now()); err != nil {
+			return nil, err
+		}
+	} else {
+		// the watch starts after the last sequence, the missing ones among
+		// the previous may belong to transactions still running.
+		var latest []int64
+		err := r.db.WithContext(ctx).Model(&OutboxMessage{}).Order("sequence DESC").Limit(watchMaxGaps).Pluck("sequence", &latest).Error
+		if err != nil {
+			return nil, err
+		}
+
+		cursor = &outboxCursor{gaps: map[int64]time.Time{}}
+		now := time.Now()
+		for i := len(latest) - 1; i >= 0; i-- {
+			cursor.read(latest[i], now)
+		}
+	}
+
+	changes := make(chan interfaces.UserChange)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+
+		fail := func(err error) {
+			if ctx.Err() == nil {
+				sendChange(ctx, changes, interfaces.UserChange{Err: err})
+			}
+		}
+
+		for {
+			stmt := r.db.WithContext(ctx).Where("sequence > ?", cursor.after)
+			if gaps := cursor.pending(); len(gaps) > 0 {
+				stmt = stmt.Or("sequence IN ?", gaps)
+			}
+
+			var messages []OutboxMessage
+			if err := stmt.Order("sequence").Limit(utils.MaxLimit).Find(&messages).Error; err != nil {
+				fail(err)
+				return
+			}
+
+			for _, m := range messages {
+				cursor.read(m.Sequence, time.Now())
+
+				event := m.Event()
+
+				var user *interfaces.User
+				if filters.HasRanges() && event.Type != interfaces.UserDeleted {
+					found, err := r.GetById(ctx, event.UserID)
+					if err != nil {
+						fail(err)
+						return
+					}
+
+					// the gorm repositories return a zero user for the missing ids.
+					if found != nil && found.ID != 0 {
+						user = found
+					}
+				}
+
+				if !filters.Matches(event, user) {
+					continue
+				}
+
+				if !sendChange(ctx, changes, interfaces.UserChange{UserEvent: event, Token: cursor.token()}) {
+					return
+				}
+			}
+
+			cursor.expire(time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// outboxCursor is the position of a watch of the outbox: the last sequence
+// read and the missing ones before it, with when they were found missing.
+type outboxCursor struct {
+	after int64
+	gaps  map[int64]time.Time
+}
+
+// parseOutboxCursor reads a token, the missing sequences it holds are
+// looked for from now.
+func parseOutboxCursor(token string, now time.Time) (*outboxCursor, error) {
+	cursor := &outboxCursor{gaps: map[int64]time.Time{}}
+
+	for i, part := range strings.Split(token, ",") {
+		sequence, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid resume token " + strconv.Quote(token))
+		}
+
+		if i == 0 {
+			cursor.after = sequence
+		} else {
+			cursor.gaps[sequence] = now
+		}
+	}
+
+	return cursor, nil
+}
+
+func (c *outboxCursor) token() string {
+	parts := []string{strconv.FormatInt(c.after, 10)}
+	for _, sequence := range c.pending() {
+		parts = append(parts, strconv.FormatInt(sequence, 10))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// pending returns the missing sequences in order.
+func (c *outboxCursor) pending() []int64 {
+	gaps := make([]int64, 0, len(c.gaps))
+	for sequence := range c.gaps {
+		gaps = append(gaps, sequence)
+	}
+	slices.Sort(gaps)
+
+	return gaps
+}
+
+// read records the sequence as read, the ones skipped to reach it missing.
+func (c *outboxCursor) read(sequence int64, now time.Time) {
+	if sequence <= c.after {
+		delete(c.gaps, sequence)
+		return
+	}
+
+	for missing := max(c.after+1, sequence-watchMaxGaps); missing < sequence; missing++ {
+		c.gaps[missing] = now
+	}
+	c.after = sequence
+
+	for sequence := range c.gaps {
+		if sequence <= c.after-watchMaxGaps {
+			delete(c.gaps, sequence)
+		}
+	}
+}
+
+// expire gives up the sequences missing for longer than watchGapWindow.
+func (c *outboxCursor) expire(now time.Time) {
+	for sequence, since := range c.gaps {
+		if now.Sub(since) >= watchGapWindow {
+			delete(c.gaps, sequence)
+		}
+	}
+}
+
+// MigrateMongoWatch records the users as they were before their changes,
+// for Watch to tell which users were deleted. It needs MongoDB 6.0 or later.
+func MigrateMongoWatch(ctx context.Context, db *mongo.Database) error {
+	preImages := bson.D{{"enabled", true}}
+
+	err := db.RunCommand(ctx, bson.D{
+		{"collMod", interfaces.UserEntity.Table},
+		{"changeStreamPreAndPostImages", preImages},
+	}).Err()
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == mongoNamespaceNotFound {
+		return db.CreateCollection(ctx, interfaces.UserEntity.Table, options.CreateCollection().SetChangeStreamPreAndPostImages(preImages))
+	}
+
+	return err
+}
+
+// mongoChange is an event of a change stream on the users.
+type mongoChange struct {
+	ID struct {
+		Data string `bson:"_data"`
+	} `bson:"_id"`
+	OperationType            string              `bson:"operationType"`
+	ClusterTime              primitive.Timestamp `bson:"clusterTime"`
+	WallTime                 time.Time           `bson:"wallTime"`
+	FullDocument             *interfaces.User    `bson:"fullDocument"`
+	FullDocumentBeforeChange *interfaces.User    `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+// event translates the change to the event the outbox would hold, and
+// returns the user after it, nil once deleted.
+func (c mongoChange) event() (interfaces.UserEvent, *interfaces.User) {
+	event := interfaces.UserEvent{OccurredAt: c.WallTime}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Unix(int64(c.ClusterTime.T), 0)
+	}
+
+	known := c.FullDocument
+	if known == nil {
+		known = c.FullDocumentBeforeChange
+	}
+	if known != nil {
+		event.UserID = int64(known.ID)
+	}
+
+	switch c.OperationType {
+	case "insert":
+		event.Type = interfaces.UserCreated
+		event.Payload = userValues(c.FullDocument)
+	case "replace":
+		event.Type = interfaces.UserUpdated
+		event.Payload = userValues(c.FullDocument)
+	case "update":
+		event.Type = interfaces.UserUpdated
+		event.Payload = map[string]interface{}{}
+		for key, v := range c.UpdateDescription.UpdatedFields {
+			event.Payload[mongoColumn(key)] = v
+		}
+	case "delete":
+		event.Type = interfaces.UserDeleted
+		event.Payload = map[string]interface{}{interfaces.FieldID: uint(event.UserID)}
+	}
+
+	return event, c.FullDocument
+}
+
+// mongoColumn maps a bson key of the users to its column, the reverse of
+// mongoField.
+func mongoColumn(key string) string {
+	for _, field := range interfaces.UserFields {
+		if mongoField(field) == key {
+			return field
+		}
+	}
+
+	return key
+}
+
+// Watch follows the change stream of the users collection, which needs a
+// replica set. The driver resumes the stream on its own after the
+// transient errors, the tokens are the resume tokens of the stream. The
+// deletes only carry the user id when the collection records the users
+// before their changes, see MigrateMongoWatch.
+func (r userRepoMongo) Watch(ctx context.Context, filters interfaces.Filters) (<-chan interfaces.UserChange, error) {
+	if err := filters.ValidateWatch(); err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"operationType", bson.D{{"$in", bson.A{"insert", "update", "replace", "delete"}}}}}}},
+	}
+
+	streamOptions := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	if token := resumeToken(ctx); token != "" {
+		streamOptions.SetResumeAfter(bson.D{{"_data", token}})
+	}
+
+	stream, err := r.collection.Watch(ctx, pipeline, streamOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan interfaces.UserChange)
+
+	go func() {
+		defer close(changes)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			var change mongoChange
+			if err := stream.Decode(&change); err != nil {
+				sendChange(ctx, changes, interfaces.UserChange{Err: err})
+				return
+			}
+
+			event, user := change.event()
+			if !filters.Matches(event, user) {
+				continue
+			}
+
+			if !sendChange(ctx, changes, interfaces.UserChange{UserEvent: event, Token: change.ID.Data}) {
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			sendChange(ctx, changes, interfaces.UserChange{Err: err})
+		}
+	}()
+
+	return changes, nil
+}
@@ -0,0 +1,277 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"repos/fixtures"
+	"repos/interfaces"
+	"repos/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// nextChange waits for the next change, failing the test on errors.
+func nextChange(t *testing.T, changes <-chan interfaces.UserChange) interfaces.UserChange {
+	t.Helper()
+
+	select {
+	case change, ok := <-changes:
+		if !ok {
+			t.Fatalf("watch closed")
+		}
+
+		if change.Err != nil {
+			t.Fatalf("watching users: %v", change.Err)
+		}
+
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change received")
+	}
+
+	return interfaces.UserChange{}
+}
+
+// writeOutbox writes message to store outside of any transaction.
+func writeOutbox(t *testing.T, store repositories.OutboxStore, message repositories.OutboxMessage) {
+	t.Helper()
+
+	if err := store.Write(context.Background(), nil, message); err != nil {
+		t.Fatalf("writing outbox: %v", err)
+	}
+}
+
+// testUserRepoWatch checks the changes watched on r while writer, writing
+// to the same database, changes the users.
+func testUserRepoWatch(t *testing.T, r interfaces.UsersRepo, writer interfaces.UsersRepo) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := r.(interfaces.Watcher)
+
+	changes, err := watcher.Watch(ctx, interfaces.Filters{})
+	if err != nil {
+		t.Fatalf("watching users: %v", err)
+	}
+
+	adults, err := watcher.Watch(ctx, interfaces.Filters{AgeGte: 18})
+	if err != nil {
+		t.Fatalf("watching users: %v", err)
+	}
+
+	if err := writer.Create(ctx, &interfaces.User{ID: 100, Name: "john", Age: 12}); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	if err := writer.Update(ctx, &interfaces.User{ID: 100}, map[string]interface{}{interfaces.FieldName: "renamed"}); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+
+	if err := writer.Create(ctx, &interfaces.User{ID: 101, Name: "jane", Age: 30}); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	if err := writer.Delete(ctx, []int64{100}); err != nil {
+		t.Fatalf("deleting user: %v", err)
+	}
+
+	var created interfaces.UserChange
+
+	t.Run("every change", func(t *testing.T) {
+		created = nextChange(t, changes)
+		updated := nextChange(t, changes)
+		other := nextChange(t, changes)
+		deleted := nextChange(t, changes)
+
+		assert.Equal(t, []interfaces.UserEventType{interfaces.UserCreated, interfaces.UserUpdated, interfaces.UserCreated, interfaces.UserDeleted}, []interfaces.UserEventType{created.Type, updated.Type, other.Type, deleted.Type}, "they should be equal")
+		assert.Equal(t, []int64{100, 100, 101, 100}, []int64{created.UserID, updated.UserID, other.UserID, deleted.UserID}, "they should be equal")
+		assert.EqualValues(t, "john", created.Payload[interfaces.FieldName], "they should be equal")
+		assert.EqualValues(t, "renamed", updated.Payload[interfaces.FieldName], "they should be equal")
+		assert.NotEmpty(t, created.Token, "should not be empty")
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		adult := nextChange(t, adults)
+
+		assert.Equal(t, interfaces.UserCreated, adult.Type, "they should be equal")
+		assert.Equal(t, int64(101), adult.UserID, "they should be equal")
+	})
+
+	t.Run("resumed", func(t *testing.T) {
+		resumed, err := watcher.Watch(repositories.WithResumeToken(ctx, created.Token), interfaces.Filters{IDs: []int64{100}})
+		if err != nil {
+			t.Fatalf("watching users: %v", err)
+		}
+
+		assert.Equal(t, interfaces.UserUpdated, nextChange(t, resumed).Type, "they should be equal")
+		assert.Equal(t, interfaces.UserDeleted, nextChange(t, resumed).Type, "they should be equal")
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		_, err := watcher.Watch(ctx, interfaces.Filters{AgeGte: 30, AgeLte: 18})
+
+		assert.Error(t, err, "should fail")
+	})
+
+	t.Run("closed with the context", func(t *testing.T) {
+		cancel()
+
+		assert.Eventually(t, func() bool {
+			_, ok := <-changes
+			return !ok
+		}, 5*time.Second, 10*time.Millisecond, "should be closed")
+	})
+}
+
+func TestUserSQLiteRepoWatch(t *testing.T) {
+	ctx := context.Background()
+	db := NewTestSQLite(ctx, t)
+
+	if err := repositories.MigrateOutbox(ctx, db); err != nil {
+		t.Fatalf("creating outbox table: %v", err)
+	}
+
+	r := repositories.NewUserRepoSQLite(db)
+
+	testUserRepoWatch(t, r, repositories.NewUserRepoOutbox(r, repositories.NewOutboxStoreMysql(db)))
+}
+
+func TestUserSQLiteRepoWatchMissingUsers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := NewTestSQLite(ctx, t)
+
+	if err := repositories.MigrateOutbox(ctx, db); err != nil {
+		t.Fatalf("creating outbox table: %v", err)
+	}
+
+	r := repositories.NewUserRepoSQLite(db)
+	store := repositories.NewOutboxStoreMysql(db)
+	writer := repositories.NewUserRepoOutbox(r, store)
+
+	changes, err := r.(interfaces.Watcher).Watch(ctx, interfaces.Filters{AgeLte: 30})
+	if err != nil {
+		t.Fatalf("watching users: %v", err)
+	}
+
+	// deleted before the poll, the created user is missing by then.
+	writeOutbox(t, store, repositories.OutboxMessage{Type: interfaces.UserCreated, UserID: 100, OccurredAt: outboxNow})
+
+	if err := writer.Create(ctx, &interfaces.User{ID: 101, Name: "kept", Age: 20}); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	change := nextChange(t, changes)
+
+	assert.Equal(t, interfaces.UserCreated, change.Type, "they should be equal")
+	assert.Equal(t, int64(101), change.UserID, "they should be equal")
+}
+
+func TestUserSQLiteRepoWatchGaps(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := NewTestSQLite(ctx, t)
+
+	if err := repositories.MigrateOutbox(ctx, db); err != nil {
+		t.Fatalf("creating outbox table: %v", err)
+	}
+
+	r := repositories.NewUserRepoSQLite(db)
+	store := repositories.NewOutboxStoreMysql(db)
+	watcher := r.(interfaces.Watcher)
+
+	write := func(sequence, userID int64) {
+		writeOutbox(t, store, repositories.OutboxMessage{Sequence: sequence, Type: interfaces.UserCreated, UserID: userID, OccurredAt: outboxNow})
+	}
+
+	changes, err := watcher.Watch(ctx, interfaces.Filters{})
+	if err != nil {
+		t.Fatalf("watching users: %v", err)
+	}
+
+	// the transaction of the first sequence commits after the second.
+	write(2, 2)
+	second := nextChange(t, changes)
+
+	assert.Equal(t, int64(2), second.UserID, "they should be equal")
+
+	resumed, err := watcher.Watch(repositories.WithResumeToken(ctx, second.Token), interfaces.Filters{})
+	if err != nil {
+		t.Fatalf("watching users: %v", err)
+	}
+
+	write(1, 1)
+	write(3, 3)
+
+	for _, watch := range []<-chan interfaces.UserChange{changes, resumed} {
+		assert.Equal(t, int64(1), nextChange(t, watch).UserID, "they should be equal")
+		assert.Equal(t, int64(3), nextChange(t, watch).UserID, "they should be equal")
+	}
+}
+
+func TestUserSQLiteRepoWatchInFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := NewTestSQLite(ctx, t)
+
+	if err := repositories.MigrateOutbox(ctx, db); err != nil {
+		t.Fatalf("creating outbox table: %v", err)
+	}
+
+	r := repositories.NewUserRepoSQLite(db)
+	store := repositories.NewOutboxStoreMysql(db)
+
+	// the transaction of the first sequence is still running when the
+	// watch starts.
+	writeOutbox(t, store, repositories.OutboxMessage{Sequence: 2, Type: interfaces.UserCreated, UserID: 2, OccurredAt: outboxNow})
+
+	changes, err := r.(interfaces.Watcher).Watch(ctx, interfaces.Filters{})
+	if err != nil {
+		t.Fatalf("watching users: %v", err)
+	}
+
+	writeOutbox(t, store, repositories.OutboxMessage{Sequence: 1, Type: interfaces.UserCreated, UserID: 1, OccurredAt: outboxNow})
+	writeOutbox(t, store, repositories.OutboxMessage{Sequence: 3, Type: interfaces.UserCreated, UserID: 3, OccurredAt: outboxNow})
+
+	assert.Equal(t, int64(1), nextChange(t, changes).UserID, "they should be equal")
+	assert.Equal(t, int64(3), nextChange(t, changes).UserID, "they should be equal")
+}
+
+func TestUserMysqlRepoWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMysql(t, &gorm.Config{})
+
+	if err := repositories.MigrateOutbox(ctx, db); err != nil {
+		t.Fatalf("creating outbox table: %v", err)
+	}
+
+	r := repositories.NewUserRepoMysql(db)
+
+	testUserRepoWatch(t, r, repositories.NewUserRepoOutbox(r, repositories.NewOutboxStoreMysql(db)))
+}
+
+func TestUserMongoRepoWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := newTestMongo(t)
+
+	if err := repositories.MigrateMongoWatch(ctx, db); err != nil {
+		t.Fatalf("recording pre-images: %v", err)
+	}
+
+	r := repositories.NewUserRepoMongo(db)
+	fixtures.Setup(t, r, fixtures.Users()...)
+
+	testUserRepoWatch(t, r, r)
+}
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"gorm.io/gorm/logger"
+)
+
+// QueryLogger logs the statements of every backend with the same fields:
+// backend, statement, duration, rows and error.
+type QueryLogger struct {
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+	// Level of the statements that are neither slow nor failed.
+	Level slog.Level
+	// SlowThreshold logs the statements taking longer at warn level, zero
+	// disables it.
+	SlowThreshold time.Duration
+	// LogParams logs the values sent with the statements. They may hold
+	// personal data like the name of the users, so they are replaced by ?
+	// unless it is set.
+	LogParams bool
+}
+
+func (q QueryLogger) logger() *slog.Logger {
+	if q.Logger == nil {
+		return slog.Default()
+	}
+
+	return q.Logger
+}
+
+// log renders the statement only when its level is enabled.
+func (q QueryLogger) log(ctx context.Context, backend string, duration time.Duration, err error, statement func() (string, int64)) {
+	level, msg := q.Level, "query"
+	switch {
+	case err != nil:
+		level, msg = slog.LevelError, "query failed"
+	case q.SlowThreshold > 0 && duration > q.SlowThreshold:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+
+	l := q.logger()
+	if !l.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := statement()
+
+	attrs := []slog.Attr{
+		slog.String("backend", backend),
+		slog.String("statement", sql),
+		slog.Duration("duration", duration),
+		slog.Int64("rows", rows),
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	l.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// Gorm returns the gorm logger to set in gorm.Config, backend names the
+// dialect in the logs.
+func (q QueryLogger) Gorm(backend string) logger.Interface {
+	return gormLogger{query: q, backend: backend}
+}
+
+type gormLogger struct {
+	query   QueryLogger
+	backend string
+}
+
+// LogMode is a no-op, the level is set by QueryLogger.
+func (l gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.query.logger().InfoContext(ctx, fmt.Sprintf(msg, args...), slog.String("backend", l.backend))
+}
+
+func (l gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.query.logger().WarnContext(ctx, fmt.Sprintf(msg, args...), slog.String("backend", l.backend))
+}
+
+func (l gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.query.logger().ErrorContext(ctx, fmt.Sprintf(msg, args...), slog.String("backend", l.backend))
+}
+
+func (l gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if errors.Is(err, logger.ErrRecordNotFound) {
+		err = nil
+	}
+
+	l.query.log(ctx, l.backend, time.Since(begin), err, fc)
+}
+
+// ParamsFilter keeps the placeholders in the logged statements unless LogParams is set.
+func (l gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.query.LogParams {
+		return sql, params
+	}
+
+	return sql, nil
+}
+
+// Mongo returns the command monitor to set with options.Client().SetMonitor,
+// combine it with others through Monitors.
+func (q QueryLogger) Mongo() *event.CommandMonitor {
+	started := sync.Map{}
+
+	finish := func(ctx context.Context, evt event.CommandFinishedEvent, rows int64, err error) {
+		statement, _ := started.LoadAndDelete(evt.RequestID)
+		if statement == nil {
+			statement = evt.CommandName
+		}
+
+		q.log(ctx, "mongodb", evt.Duration, err, func() (string, int64) {
+			return statement.(string), rows
+		})
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			started.Store(evt.RequestID, mongoStatement(evt.Command, q.LogParams))
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			finish(ctx, evt.CommandFinishedEvent, mongoRows(evt.Reply), nil)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			finish(ctx, evt.CommandFinishedEvent, 0, errors.New(evt.Failure))
+		},
+	}
+}
+
+// mongoPayloads are the command fields holding user data.
+var mongoPayloads = map[string]bool{
+	"filter":    true,
+	"documents": true,
+	"updates":   true,
+	"deletes":   true,
+	"pipeline":  true,
+	"query":     true,
+}
+
+// mongoStatement renders the command without the session and cluster
+// fields, replacing the values of the payloads by ? unless params is set.
+func mongoStatement(command bson.Raw, params bool) string {
+	var doc bson.D
+	if err := bson.Unmarshal(command, &doc); err != nil {
+		return ""
+	}
+
+	statement := bson.D{}
+	for _, e := range doc {
+		if strings.HasPrefix(e.Key, "$") || e.Key == "lsid" || e.Key == "txnNumber" {
+			continue
+		}
+
+		if !params {
+			e.Value = redactPayload(e.Key, e.Value)
+		}
+
+		statement = append(statement, e)
+	}
+
+	data, err := bson.MarshalExtJSON(statement, false, false)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+// redactPayload redacts the value of the command field key when it holds
+// user data, or the payloads of the command explained.
+func redactPayload(key string, v interface{}) interface{} {
+	if mongoPayloads[key] {
+		return redact(v)
+	}
+
+	explained, ok := v.(bson.D)
+	if key != "explain" || !ok {
+		return v
+	}
+
+	d := make(bson.D, len(explained))
+	for i, e := range explained {
+		d[i] = bson.E{Key: e.Key, Value: redactPayload(e.Key, e.Value)}
+	}
+
+	return d
+}
+
+// redact replaces every value of v by ?, keeping the keys that name the
+// fields and operators.
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.D:
+		d := make(bson.D, len(v))
+		for i, e := range v {
+			d[i] = bson.E{Key: e.Key, Value: redact(e.Value)}
+		}
+		return d
+	case bson.A:
+		a := make(bson.A, len(v))
+		for i, e := range v {
+			a[i] = redact(e)
+		}
+		return a
+	default:
+		return "?"
+	}
+}
+
+// mongoRows reads the affected documents of writes and the first batch of reads.
+func mongoRows(reply bson.Raw) int64 {
+	if n, ok := reply.Lookup("n").AsInt64OK(); ok {
+		return n
+	}
+
+	if batch, ok := reply.Lookup("cursor", "firstBatch").ArrayOK(); ok {
+		values, _ := batch.Values()
+		return int64(len(values))
+	}
+
+	return 0
+}
+
+// Monitors calls every monitor on each command event.
+func Monitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, evt)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, evt)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, evt)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,177 @@
+package utils_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"repos/utils"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"gorm.io/gorm"
+)
+
+func newQueryLogger(level slog.Level, logParams bool) (utils.QueryLogger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+
+	return utils.QueryLogger{
+		Logger:        slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Level:         level,
+		SlowThreshold: 100 * time.Millisecond,
+		LogParams:     logParams,
+	}, buf
+}
+
+func lastRecord(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(lines[len(lines)-1], &record); err != nil {
+		t.Fatalf("decoding log: %v", err)
+	}
+
+	return record
+}
+
+func TestQueryLoggerGorm(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("query", func(t *testing.T) {
+		q, buf := newQueryLogger(slog.LevelDebug, false)
+		l := q.Gorm("mysql")
+
+		l.Trace(ctx, time.Now(), func() (string, int64) {
+			return "SELECT * FROM `users` WHERE name = ?", 2
+		}, nil)
+
+		record := lastRecord(t, buf)
+		assert.Equal(t, "DEBUG", record["level"], "they should be equal")
+		assert.Equal(t, "query", record["msg"], "they should be equal")
+		assert.Equal(t, "mysql", record["backend"], "they should be equal")
+		assert.Equal(t, "SELECT * FROM `users` WHERE name = ?", record["statement"], "they should be equal")
+		assert.Equal(t, float64(2), record["rows"], "they should be equal")
+	})
+
+	t.Run("slow query", func(t *testing.T) {
+		q, buf := newQueryLogger(slog.LevelDebug, false)
+
+		q.Gorm("mysql").Trace(ctx, time.Now().Add(-time.Second), func() (string, int64) { return "SELECT 1", 1 }, nil)
+
+		record := lastRecord(t, buf)
+		assert.Equal(t, "WARN", record["level"], "they should be equal")
+		assert.Equal(t, "slow query", record["msg"], "they should be equal")
+	})
+
+	t.Run("failed query", func(t *testing.T) {
+		q, buf := newQueryLogger(slog.LevelDebug, false)
+
+		q.Gorm("mysql").Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 0 }, errors.New("deadlock"))
+
+		record := lastRecord(t, buf)
+		assert.Equal(t, "ERROR", record["level"], "they should be equal")
+		assert.Equal(t, "deadlock", record["error"], "they should be equal")
+	})
+
+	t.Run("disabled level", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		q := utils.QueryLogger{Logger: slog.New(slog.NewJSONHandler(buf, nil)), Level: slog.LevelDebug}
+
+		q.Gorm("mysql").Trace(ctx, time.Now(), func() (string, int64) {
+			t.Fatal("statement rendered for a disabled level")
+			return "", 0
+		}, nil)
+
+		assert.Equal(t, 0, buf.Len(), "they should be equal")
+	})
+
+	t.Run("params", func(t *testing.T) {
+		q, _ := newQueryLogger(slog.LevelDebug, false)
+		redacted, _ := q.Gorm("mysql").(gorm.ParamsFilter).ParamsFilter(ctx, "name = ?", "John Doe")
+
+		q, _ = newQueryLogger(slog.LevelDebug, true)
+		_, params := q.Gorm("mysql").(gorm.ParamsFilter).ParamsFilter(ctx, "name = ?", "John Doe")
+
+		assert.Equal(t, "name = ?", redacted, "they should be equal")
+		assert.Equal(t, []interface{}{"John Doe"}, params, "they should be equal")
+	})
+}
+
+func TestQueryLoggerMongo(t *testing.T) {
+	ctx := context.Background()
+
+	command, _ := bson.Marshal(bson.D{
+		{Key: "insert", Value: "users"},
+		{Key: "documents", Value: bson.A{bson.D{{Key: "name", Value: "John Doe"}, {Key: "age", Value: 5}}}},
+		{Key: "$db", Value: "test"},
+	})
+	reply, _ := bson.Marshal(bson.D{{Key: "n", Value: int32(1)}, {Key: "ok", Value: 1}})
+
+	run := func(m *event.CommandMonitor) {
+		m.Started(ctx, &event.CommandStartedEvent{Command: command, CommandName: "insert", RequestID: 1})
+		m.Succeeded(ctx, &event.CommandSucceededEvent{
+			CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", RequestID: 1, Duration: time.Millisecond},
+			Reply:                reply,
+		})
+	}
+
+	t.Run("redacted", func(t *testing.T) {
+		q, buf := newQueryLogger(slog.LevelInfo, false)
+		run(q.Mongo())
+
+		record := lastRecord(t, buf)
+		assert.Equal(t, "mongodb", record["backend"], "they should be equal")
+		assert.Equal(t, `{"insert":"users","documents":[{"name":"?","age":"?"}]}`, record["statement"], "they should be equal")
+		assert.Equal(t, float64(1), record["rows"], "they should be equal")
+	})
+
+	t.Run("params", func(t *testing.T) {
+		q, buf := newQueryLogger(slog.LevelInfo, true)
+		run(utils.Monitors(q.Mongo(), &event.CommandMonitor{}))
+
+		record := lastRecord(t, buf)
+		assert.Equal(t, `{"insert":"users","documents":[{"name":"John Doe","age":5}]}`, record["statement"], "they should be equal")
+	})
+
+	t.Run("explain redacted", func(t *testing.T) {
+		q, buf := newQueryLogger(slog.LevelInfo, false)
+		m := q.Mongo()
+
+		explain, _ := bson.Marshal(bson.D{
+			{Key: "explain", Value: bson.D{
+				{Key: "find", Value: "users"},
+				{Key: "filter", Value: bson.D{{Key: "name", Value: "John Doe"}}},
+			}},
+			{Key: "verbosity", Value: "executionStats"},
+		})
+
+		m.Started(ctx, &event.CommandStartedEvent{Command: explain, CommandName: "explain", RequestID: 3})
+		m.Succeeded(ctx, &event.CommandSucceededEvent{
+			CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "explain", RequestID: 3},
+			Reply:                reply,
+		})
+
+		record := lastRecord(t, buf)
+		assert.Equal(t, `{"explain":{"find":"users","filter":{"name":"?"}},"verbosity":"executionStats"}`, record["statement"], "they should be equal")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		q, buf := newQueryLogger(slog.LevelInfo, false)
+		m := q.Mongo()
+
+		m.Started(ctx, &event.CommandStartedEvent{Command: command, CommandName: "insert", RequestID: 2})
+		m.Failed(ctx, &event.CommandFailedEvent{
+			CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", RequestID: 2},
+			Failure:              "duplicate key",
+		})
+
+		record := lastRecord(t, buf)
+		assert.Equal(t, "ERROR", record["level"], "they should be equal")
+		assert.Equal(t, "duplicate key", record["error"], "they should be equal")
+	})
+}
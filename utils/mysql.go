@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"reflect"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,6 +12,8 @@ import (
 
 const (
 	Limit       = 30
+	MaxLimit    = 100
+	IDsChunk    = 1000
 	MaxInterval = time.Hour * 24 * 10
 )
 
@@ -18,6 +21,7 @@ type options struct {
 	Tx         *gorm.DB
 	Lock       bool
 	FromMaster bool
+	Fields     []string
 }
 
 type Options func(*options)
@@ -40,10 +44,67 @@ func WithLock(c *options) {
 	c.Lock = true
 }
 
+// WithoutTx drops the transaction and the lock set by the previous options,
+// for the statements that must not join them.
+func WithoutTx(c *options) {
+	c.Tx = nil
+	c.Lock = false
+}
+
 func FromMasterReplica(c *options) {
 	c.FromMaster = true
 }
 
+// HasTx reports whether the clauses run inside a transaction given with WithTx.
+func HasTx(clauses ...Options) bool {
+	return Tx(clauses...) != nil
+}
+
+// Tx returns the transaction given with WithTx, nil without one.
+func Tx(clauses ...Options) *gorm.DB {
+	q := defaultClause()
+
+	for _, fn := range clauses {
+		fn(&q)
+	}
+
+	return q.Tx
+}
+
+// WithFields restricts the columns loaded by the reads, the writes ignore it.
+func WithFields(fields ...string) Options {
+	return func(c *options) {
+		c.Fields = fields
+	}
+}
+
+// Fields returns the columns requested with WithFields.
+func Fields(clauses ...Options) []string {
+	q := defaultClause()
+
+	for _, fn := range clauses {
+		fn(&q)
+	}
+
+	return q.Fields
+}
+
+// EqualOptions reports whether both lists of clauses configure the
+// statements the same way, options being functions they can not be compared.
+func EqualOptions(a, b []Options) bool {
+	qa, qb := defaultClause(), defaultClause()
+
+	for _, fn := range a {
+		fn(&qa)
+	}
+
+	for _, fn := range b {
+		fn(&qb)
+	}
+
+	return reflect.DeepEqual(qa, qb)
+}
+
 func ConfigureDB(db *gorm.DB, clauses ...Options) *gorm.DB {
 	q := defaultClause()
 
@@ -51,6 +112,24 @@ func ConfigureDB(db *gorm.DB, clauses ...Options) *gorm.DB {
 		fn(&q)
 	}
 
+	return configure(db, q)
+}
+
+// ConfigureWriteDB configures the statements of the writes, which ignore
+// WithFields: the created rows and the updated values are written whole.
+func ConfigureWriteDB(db *gorm.DB, clauses ...Options) *gorm.DB {
+	q := defaultClause()
+
+	for _, fn := range clauses {
+		fn(&q)
+	}
+
+	q.Fields = nil
+
+	return configure(db, q)
+}
+
+func configure(db *gorm.DB, q options) *gorm.DB {
 	chain := db
 	if q.Tx != nil {
 		chain = q.Tx
@@ -60,6 +139,10 @@ func ConfigureDB(db *gorm.DB, clauses ...Options) *gorm.DB {
 		chain = chain.Clauses(dbresolver.Write)
 	}
 
+	if len(q.Fields) > 0 {
+		chain = chain.Select(q.Fields)
+	}
+
 	if q.Lock {
 		chain = chain.Clauses(clause.Locking{
 			Strength: "UPDATE",
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/tracing"
+)
+
+// WithTracing reports a span per statement run by db, nested under the span
+// of the context given to WithContext. Query parameters are left out since
+// they may hold personal data.
+func WithTracing(db *gorm.DB, provider trace.TracerProvider) error {
+	return db.Use(tracing.NewPlugin(
+		tracing.WithTracerProvider(provider),
+		tracing.WithoutQueryVariables(),
+	))
+}
+
+// MongoMonitor reports a span per command, to be set with
+// options.Client().SetMonitor.
+func MongoMonitor(provider trace.TracerProvider) *event.CommandMonitor {
+	return otelmongo.NewMonitor(otelmongo.WithTracerProvider(provider))
+}